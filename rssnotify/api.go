@@ -0,0 +1,82 @@
+// Package rssnotify fetches RSS and Atom feeds, filters them for entries in
+// chosen categories and delivers a digest to Slack, Discord or Teams.
+//
+// Programs embedding it call FetchAndFilter and Notify, configured through
+// Options. Main runs the rss-notifications command, which is configured from
+// flags and the environment instead.
+package rssnotify
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Options configures FetchAndFilter and Notify.
+type Options struct {
+	Categories []string      // Keep items with any of these categories; defaults to "dns"
+	Keywords   []string      // ...or whose title contains any of these, ignoring case
+	Timeout    time.Duration // Bounds each feed request; 0 uses the 30s default
+	Notifier   string        // "slack" (default), "discord", "teams", "google-chat" or "webhook"
+	WebhookURL string        // Incoming webhook of the selected notifier
+}
+
+// filters converts the options into the filters applied to feed items.
+func (opts Options) filters() filterOptions {
+	filters := filterOptions{Categories: opts.Categories}
+	if len(filters.Categories) == 0 {
+		filters.Categories = defaultCategories
+	}
+	for _, keyword := range opts.Keywords {
+		filters.Keywords = append(filters.Keywords, strings.ToLower(keyword))
+	}
+	return filters
+}
+
+// FetchAndFilter fetches the feed at url and returns its entries matching
+// the categories or keywords in opts.
+func FetchAndFilter(url string, opts Options) ([]FilteredEntry, error) {
+	return FetchAndFilterContext(context.Background(), url, opts)
+}
+
+// FetchAndFilterContext is like FetchAndFilter, but the fetch is abandoned
+// once ctx is done.
+func FetchAndFilterContext(ctx context.Context, url string, opts Options) ([]FilteredEntry, error) {
+	client := newFeedClient(0, opts.Timeout)
+	return fetchAndFilterRSSEntries(ctx, client, url, opts.filters())
+}
+
+// Notify delivers entries as a single digest via the notifier in opts. No
+// message is sent for an empty digest. Slack digests are posted to the
+// incoming webhook as a Block Kit message; the environment isn't consulted.
+func Notify(entries []FilteredEntry, opts Options) error {
+	return NotifyContext(context.Background(), entries, opts)
+}
+
+// NotifyContext is like Notify, but delivery is abandoned once ctx is done.
+func NotifyContext(ctx context.Context, entries []FilteredEntry, opts Options) error {
+	cfg := Config{Notifier: opts.Notifier}
+	switch notifierName(opts.Notifier) {
+	case "discord":
+		cfg.DiscordWebhookURL = opts.WebhookURL
+	case "teams":
+		cfg.TeamsWebhookURL = opts.WebhookURL
+	case "google-chat":
+		cfg.GoogleChatWebhookURL = opts.WebhookURL
+	case "webhook":
+		cfg.GenericWebhookURL = opts.WebhookURL
+	default:
+		cfg.SlackWebhookURL = opts.WebhookURL
+	}
+	slackOpts := slackOptions{
+		Messages:          englishMessages,
+		Layout:            layoutDefault,
+		DescriptionLength: defaultDescriptionLength,
+	}
+
+	notifier, err := newNotifier(cfg, slackOpts, newRunReport(time.Now()))
+	if err != nil {
+		return err
+	}
+	return notifier.Send(ctx, entries)
+}
@@ -0,0 +1,111 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFetchAndFilter(t *testing.T) {
+	srv := newFeedServer(t, "feed.xml")
+
+	entries, err := FetchAndFilter(srv.URL, Options{Keywords: []string{"GTLD"}})
+	if err != nil {
+		t.Fatalf("FetchAndFilter: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("got %d entries, want the 2 dns entries and the keyword match: %+v", len(entries), entries)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	srv, payloads := newSlackServer(t, http.StatusOK)
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+
+	if err := Notify(entries, Options{WebhookURL: srv.URL}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(*payloads) != 1 {
+		t.Fatalf("got %d payloads, want 1", len(*payloads))
+	}
+	if got := (*payloads)[0].Blocks[0].Text.Text; got != "📰 Daily DNS News Digest (Domain Incite)" {
+		t.Errorf("header = %q", got)
+	}
+}
+
+func TestNotifyIgnoresEnvironment(t *testing.T) {
+	srv, payloads := newSlackServer(t, http.StatusOK)
+	t.Setenv("SLACK_BOT_TOKEN", "xoxb-test")
+	t.Setenv("SLACK_CHANNEL", "C123")
+	t.Setenv("SLACK_THREADED", "true")
+	t.Setenv("SLACK_FORMAT", "workflow")
+	capturePath := t.TempDir()
+	t.Setenv("CAPTURE_DIR", capturePath)
+
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+	if err := Notify(entries, Options{WebhookURL: srv.URL}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(*payloads) != 1 || len((*payloads)[0].Blocks) == 0 {
+		t.Fatalf("payloads = %+v, want one Block Kit message posted to the webhook", *payloads)
+	}
+	if captured, _ := os.ReadDir(capturePath); len(captured) != 0 {
+		t.Errorf("captured %d files, want CAPTURE_DIR ignored", len(captured))
+	}
+}
+
+func TestZeroValueNotifiers(t *testing.T) {
+	payloads := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		payloads[r.URL.Path] = string(data)
+	}))
+	defer srv.Close()
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+
+	notifiers := map[string]Notifier{
+		"/slack":   SlackNotifier{WebhookURL: srv.URL + "/slack"},
+		"/discord": DiscordNotifier{WebhookURL: srv.URL + "/discord"},
+		"/teams":   TeamsNotifier{WebhookURL: srv.URL + "/teams"},
+		"/chat":    GoogleChatNotifier{WebhookURL: srv.URL + "/chat"},
+		"/webhook": WebhookNotifier{URL: srv.URL + "/webhook"},
+	}
+	for path, n := range notifiers {
+		if err := n.Send(t.Context(), entries); err != nil {
+			t.Errorf("%T.Send: %v", n, err)
+		}
+		if payload := payloads[path]; !strings.Contains(payload, "Registry raises prices") {
+			t.Errorf("%T payload = %s, want the entry", n, payload)
+		}
+	}
+
+	var slack SlackMessage
+	if err := json.Unmarshal([]byte(payloads["/slack"]), &slack); err != nil {
+		t.Fatal(err)
+	}
+	if slack.Text == "" || len(slack.Blocks) == 0 || slack.Blocks[0].Text == nil || slack.Blocks[0].Text.Text != englishMessages.render(englishMessages.Header, 0, "") {
+		t.Errorf("Slack payload = %s, want the English header and fallback text", payloads["/slack"])
+	}
+	var chat GoogleChatMessage
+	if err := json.Unmarshal([]byte(payloads["/chat"]), &chat); err != nil {
+		t.Fatal(err)
+	}
+	if chat.Text == "" || chat.CardsV2[0].Card.Header == nil || chat.CardsV2[0].Card.Header.Title == "" {
+		t.Errorf("Google Chat payload = %s, want the header and fallback text", payloads["/chat"])
+	}
+	for _, path := range []string{"/discord", "/teams"} {
+		if !strings.Contains(payloads[path], englishMessages.Label) {
+			t.Errorf("%s payload = %s, want the English header", path, payloads[path])
+		}
+	}
+}
+
+func TestNotifyUnknownNotifier(t *testing.T) {
+	if err := Notify(nil, Options{Notifier: "irc"}); err == nil {
+		t.Fatal("expected an error for an unknown notifier")
+	}
+}
@@ -0,0 +1,110 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	archivePrefix = "entries-"
+	archiveSuffix = ".json"
+)
+
+// archiveRecord is the content of a single run's archive file.
+type archiveRecord struct {
+	FeedURLs   []string        `json:"feed_urls"`
+	Timestamp  time.Time       `json:"timestamp"`
+	EntryCount int             `json:"entry_count"`
+	Entries    []FilteredEntry `json:"entries"`
+}
+
+// archiveRetention limits how many archive files are kept. Zero values
+// disable the corresponding limit.
+type archiveRetention struct {
+	MaxFiles int
+	MaxAge   time.Duration
+}
+
+// writeArchive writes the run's matched entries to a timestamped JSON file in
+// dir, then prunes old archive files according to retention.
+func writeArchive(dir string, feedURLs []string, entries []FilteredEntry, now time.Time, retention archiveRetention) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	if entries == nil {
+		entries = []FilteredEntry{} // Encode as [] rather than null
+	}
+	record := archiveRecord{
+		FeedURLs:   feedURLs,
+		Timestamp:  now.UTC(),
+		EntryCount: len(entries),
+		Entries:    entries,
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling archive record: %w", err)
+	}
+
+	path := filepath.Join(dir, archivePrefix+now.UTC().Format(time.RFC3339)+archiveSuffix)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing archive file: %w", err)
+	}
+	infof("Archived %d entries to %s\n", len(entries), path)
+
+	return pruneArchive(dir, now, retention)
+}
+
+// pruneArchive removes archive files beyond the configured retention limits.
+func pruneArchive(dir string, now time.Time, retention archiveRetention) error {
+	if retention.MaxFiles <= 0 && retention.MaxAge <= 0 {
+		return nil
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading archive directory: %w", err)
+	}
+
+	var names []string
+	for _, e := range dirEntries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, archivePrefix) && strings.HasSuffix(name, archiveSuffix) {
+			names = append(names, name)
+		}
+	}
+	// RFC3339 timestamps in UTC sort lexically in chronological order.
+	sort.Strings(names)
+
+	var remove []string
+	if retention.MaxFiles > 0 && len(names) > retention.MaxFiles {
+		remove = append(remove, names[:len(names)-retention.MaxFiles]...)
+		names = names[len(names)-retention.MaxFiles:]
+	}
+	if retention.MaxAge > 0 {
+		for _, name := range names {
+			stamp := strings.TrimSuffix(strings.TrimPrefix(name, archivePrefix), archiveSuffix)
+			t, err := time.Parse(time.RFC3339, stamp)
+			if err != nil {
+				continue // Not one of ours, leave it alone
+			}
+			if now.Sub(t) > retention.MaxAge {
+				remove = append(remove, name)
+			}
+		}
+	}
+
+	for _, name := range remove {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("error removing old archive file: %w", err)
+		}
+		infof("Removed old archive file: %s\n", name)
+	}
+	return nil
+}
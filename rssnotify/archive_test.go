@@ -0,0 +1,91 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWriteArchive(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	now := time.Date(2024, time.June, 1, 9, 0, 0, 0, time.UTC)
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+
+	if err := writeArchive(dir, []string{"https://domainincite.com/feed"}, entries, now, archiveRetention{}); err != nil {
+		t.Fatalf("writeArchive: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "entries-2024-06-01T09:00:00Z.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record archiveRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.EntryCount != 1 || !record.Timestamp.Equal(now) || record.Entries[0].Link != entries[0].Link || record.FeedURLs[0] != "https://domainincite.com/feed" {
+		t.Errorf("record = %+v", record)
+	}
+
+	// An empty run is archived too, with [] rather than null.
+	if err := writeArchive(dir, nil, nil, now.Add(time.Hour), archiveRetention{}); err != nil {
+		t.Fatalf("writeArchive: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "entries-2024-06-01T10:00:00Z.json"))
+	if err := json.Unmarshal(data, &record); err != nil || record.Entries == nil || record.EntryCount != 0 {
+		t.Errorf("empty record = %s, want an empty entries array", data)
+	}
+}
+
+func TestPruneArchive(t *testing.T) {
+	now := time.Date(2024, time.June, 10, 9, 0, 0, 0, time.UTC)
+	days := []int{9, 5, 2, 1, 0}
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		for _, ago := range days {
+			name := archivePrefix + now.AddDate(0, 0, -ago).Format(time.RFC3339) + archiveSuffix
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644) // Not an archive file
+		return dir
+	}
+	remaining := func(dir string) []string {
+		var names []string
+		dirEntries, _ := os.ReadDir(dir)
+		for _, e := range dirEntries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+
+	tests := []struct {
+		name      string
+		retention archiveRetention
+		want      int
+	}{
+		{"unlimited", archiveRetention{}, 6},
+		{"max files", archiveRetention{MaxFiles: 2}, 3},
+		{"max age", archiveRetention{MaxAge: 3 * 24 * time.Hour}, 4},
+		{"both", archiveRetention{MaxFiles: 4, MaxAge: 24 * time.Hour}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setup(t)
+			if err := pruneArchive(dir, now, tt.retention); err != nil {
+				t.Fatalf("pruneArchive: %v", err)
+			}
+			names := remaining(dir)
+			if len(names) != tt.want || !slices.Contains(names, "notes.txt") {
+				t.Errorf("remaining = %v, want %d files including notes.txt", names, tt.want)
+			}
+			newest := archivePrefix + now.Format(time.RFC3339) + archiveSuffix
+			if !slices.Contains(names, newest) {
+				t.Errorf("remaining = %v, want the newest archive kept", names)
+			}
+		})
+	}
+}
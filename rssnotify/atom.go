@@ -0,0 +1,164 @@
+package rssnotify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// atomNamespace is the XML namespace of Atom 1.0 documents.
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// Atom structure definitions for XML parsing
+// See: https://www.rfc-editor.org/rfc/rfc4287
+type Atom struct {
+	XMLName xml.Name    `xml:"feed"` // Matched by name alone, see parseFeed
+	Title   string      `xml:"title"`
+	Links   []AtomLink  `xml:"link"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+// AtomEntry is an individual Atom entry
+type AtomEntry struct {
+	Media                     // Declared before Content, which would also capture <media:content>
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []AtomLink     `xml:"link"`
+	Categories []AtomCategory `xml:"category"`
+	Summary    AtomText       `xml:"summary"`
+	Content    AtomText       `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Extra      []extraElement `xml:",any"` // Other elements, which DEDUP_FIELD paths can name
+}
+
+// AtomCategory carries its value in the term attribute
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// AtomText is a text construct whose type is "text", "html" or "xhtml"
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"` // Content of "text" and (escaped) "html" types
+	Inner string `xml:",innerxml"` // Content of "xhtml" types, which embed markup
+}
+
+// String returns the construct's content as text or HTML.
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return t.Inner
+	}
+	return t.Text
+}
+
+// rootElement returns the name of the document's root element.
+func rootElement(body []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("error finding root element: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// feedMeta describes the feed as a whole.
+type feedMeta struct {
+	Title string // Human-readable feed title
+	Link  string // The website the feed belongs to
+
+	Skipped int // Malformed items dropped while parsing
+}
+
+// parseFeed parses an RSS 2.0 or Atom document, dispatching on the root
+// element, and returns its items along with details of the feed. Atom
+// entries are mapped onto Item so every filter treats both formats alike.
+// Documents that fail to parse as a whole are decoded again item by item, so
+// malformed items are skipped instead of failing the feed. A <feed> root is
+// Atom whatever its namespace, as some feeds omit it or declare an older one.
+func parseFeed(body []byte) (items []Item, meta feedMeta, err error) {
+	root, err := rootElement(body)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	switch {
+	case root.Local == "feed":
+		if root.Space != atomNamespace {
+			debugf("Parsing <feed> in namespace %q as Atom\n", root.Space)
+		}
+		var atomData Atom
+		if err := xml.Unmarshal(body, &atomData); err != nil {
+			warnf("Warning: Atom feed is malformed, decoding entry by entry: %v\n", err)
+			if atomData, meta.Skipped, err = parseAtomTolerant(body); err != nil {
+				return nil, meta, fmt.Errorf("error parsing XML from Atom feed: %w", err)
+			}
+		}
+		for _, entry := range atomData.Entries {
+			items = append(items, entry.toItem())
+		}
+		meta.Title = strings.TrimSpace(atomData.Title)
+		meta.Link = alternateLink(atomData.Links)
+		return items, meta, nil
+
+	case root.Local == "rss":
+		var rssData RSS
+		if err := xml.Unmarshal(body, &rssData); err != nil {
+			warnf("Warning: RSS feed is malformed, decoding item by item: %v\n", err)
+			if rssData.Channel, meta.Skipped, err = parseRSSTolerant(body); err != nil {
+				return nil, meta, fmt.Errorf("error parsing XML from RSS feed: %w", err)
+			}
+		}
+		meta.Title = strings.TrimSpace(rssData.Channel.Title)
+		meta.Link = strings.TrimSpace(rssData.Channel.Link)
+		return rssData.Channel.Items, meta, nil
+
+	default:
+		return nil, meta, fmt.Errorf("unsupported feed format: root element <%s>", root.Local)
+	}
+}
+
+// toItem maps an Atom entry onto the RSS item structure.
+func (e AtomEntry) toItem() Item {
+	item := Item{
+		Title:       e.Title,
+		Link:        alternateLink(e.Links),
+		GUID:        GUID{Value: e.ID, IsPermaLink: "false"}, // Atom ids are often URNs
+		Description: e.Summary.String(),
+		Content:     e.Content.String(),
+		PubDate:     e.Published,
+		Media:       e.Media,
+		Extra:       e.Extra,
+	}
+	if item.PubDate == "" {
+		item.PubDate = e.Updated // Required by Atom, unlike published
+	}
+	for _, cat := range e.Categories {
+		item.Categories = append(item.Categories, Category{Term: cat.Term})
+	}
+	for _, link := range e.Links {
+		switch link.Rel {
+		case "replies":
+			item.AtomLinks = append(item.AtomLinks, link)
+		case "enclosure":
+			item.Enclosures = append(item.Enclosures, Enclosure{URL: link.Href, Type: link.Type, Length: link.Length})
+		}
+	}
+	return item
+}
+
+// alternateLink returns the href of the first rel="alternate" link, which is
+// the default when rel is omitted.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
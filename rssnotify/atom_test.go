@@ -0,0 +1,69 @@
+package rssnotify
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseFeedSkipsMalformedItems(t *testing.T) {
+	body, err := os.ReadFile("testdata/malformed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, meta, err := parseFeed(body)
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+
+	if meta.Skipped != 1 {
+		t.Errorf("skipped = %d, want 1", meta.Skipped)
+	}
+	if meta.Title != "Domain Incite" {
+		t.Errorf("title = %q, want Domain Incite", meta.Title)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	if items[0].Link != "https://domainincite.com/1" || items[1].Link != "https://domainincite.com/3" {
+		t.Errorf("links = %q, %q", items[0].Link, items[1].Link)
+	}
+	// Namespaces declared on the root still resolve after skipping.
+	if got := items[1].discussLink(); got != "https://domainincite.com/3/feed" {
+		t.Errorf("discuss link = %q", got)
+	}
+}
+
+func TestParseFeedSkipsMalformedAtomEntries(t *testing.T) {
+	body := []byte(`<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example</title>
+  <entry><title>Broken <b></title><link href="https://example.com/1"/></entry>
+  <entry><title>Fine</title><link href="https://example.com/2"/></entry>
+</feed>`)
+
+	items, meta, err := parseFeed(body)
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	if meta.Skipped != 1 || len(items) != 1 || items[0].Link != "https://example.com/2" {
+		t.Errorf("got %d items (skipped %d): %+v", len(items), meta.Skipped, items)
+	}
+}
+
+func TestParseFeedDetectsAtomByRootElement(t *testing.T) {
+	for name, root := range map[string]string{
+		"namespace":    `<feed xmlns="http://www.w3.org/2005/Atom">`,
+		"no namespace": `<feed>`,
+		"atom 0.3":     `<feed version="0.3" xmlns="http://purl.org/atom/ns#">`,
+	} {
+		body := []byte(root + `<title>Example</title><entry><title>Fine</title><link href="https://example.com/1"/><category term="dns"/></entry></feed>`)
+		items, meta, err := parseFeed(body)
+		if err != nil {
+			t.Errorf("%s: parseFeed: %v", name, err)
+			continue
+		}
+		if meta.Title != "Example" || meta.Skipped != 0 || len(items) != 1 || items[0].Link != "https://example.com/1" {
+			t.Errorf("%s: got %+v (meta %+v), want one Atom entry", name, items, meta)
+		}
+	}
+}
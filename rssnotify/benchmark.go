@@ -0,0 +1,63 @@
+package rssnotify
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// runBenchmark parses and filters the feed body n times, reporting timing and
+// allocation statistics. It never sends notifications.
+func runBenchmark(body []byte, n int, filters filterOptions) error {
+	if n <= 0 {
+		return fmt.Errorf("benchmark iterations must be positive, got %d", n)
+	}
+
+	// Silence per-entry logging so it doesn't dominate the measurements,
+	// whether it goes through the log package or, with LOG_FORMAT=json,
+	// slog. Restoring the slog default may reroute the log package, so its
+	// output is restored last.
+	defaultLogger, output, flags := slog.Default(), log.Writer(), log.Flags()
+	defer func() {
+		slog.SetDefault(defaultLogger)
+		log.SetOutput(output)
+		log.SetFlags(flags)
+	}()
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	log.SetOutput(io.Discard)
+
+	var entryCount int
+	durations := make([]time.Duration, n)
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	for i := range n {
+		start := time.Now()
+		entries, err := filterRSSEntries(body, filters)
+		durations[i] = time.Since(start)
+		if err != nil {
+			return err
+		}
+		entryCount = len(entries)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	fmt.Printf("iterations: %d\n", n)
+	fmt.Printf("feed size:  %d bytes\n", len(body))
+	fmt.Printf("entries:    %d\n", entryCount)
+	fmt.Printf("min:        %s\n", durations[0])
+	fmt.Printf("median:     %s\n", durations[n/2])
+	fmt.Printf("max:        %s\n", durations[n-1])
+	fmt.Printf("allocs/op:  %d\n", (after.Mallocs-before.Mallocs)/uint64(n))
+	fmt.Printf("bytes/op:   %d\n", (after.TotalAlloc-before.TotalAlloc)/uint64(n))
+	return nil
+}
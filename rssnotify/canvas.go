@@ -0,0 +1,150 @@
+package rssnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// slackCanvasContent is a canvas document written as markdown.
+type slackCanvasContent struct {
+	Type     string `json:"type"` // Always "markdown"
+	Markdown string `json:"markdown"`
+}
+
+// slackChannelCanvasRequest is the payload for conversations.canvases.create.
+// See: https://api.slack.com/methods/conversations.canvases.create
+type slackChannelCanvasRequest struct {
+	ChannelID       string             `json:"channel_id"`
+	DocumentContent slackCanvasContent `json:"document_content"`
+}
+
+// slackCanvasEditRequest is the payload for canvases.edit.
+// See: https://api.slack.com/methods/canvases.edit
+type slackCanvasEditRequest struct {
+	CanvasID string              `json:"canvas_id"`
+	Changes  []slackCanvasChange `json:"changes"`
+}
+
+// slackCanvasChange is a single edit of a canvas.
+type slackCanvasChange struct {
+	Operation       string             `json:"operation"` // "replace" without a section_id replaces the whole canvas
+	DocumentContent slackCanvasContent `json:"document_content"`
+}
+
+// markdownEscaper escapes the characters canvas markdown would otherwise
+// treat as formatting in feed-supplied text.
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`")
+
+// buildCanvasMarkdown renders the digest as the markdown of a canvas, with
+// the time of the update under the header since the canvas outlives the run.
+func buildCanvasMarkdown(entries []FilteredEntry, opts slackOptions, now time.Time) string {
+	location := opts.Location
+	if location == nil {
+		location = time.Local
+	}
+	opts.Location = location // For formatPublished
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscaper.Replace(opts.headerText()))
+	fmt.Fprintf(&b, "_Updated %s_\n\n", now.In(location).Format("Jan 2, 2006 15:04 MST"))
+	if len(entries) == 0 {
+		b.WriteString(markdownEscaper.Replace(opts.Messages.Empty) + "\n")
+		return b.String()
+	}
+	for _, entry := range entries {
+		// The link is used as is, as escaping would change it.
+		fmt.Fprintf(&b, "- %s[%s](%s)", entry.changeMarker(), markdownEscaper.Replace(entry.Title), entry.Link)
+		var meta []string
+		if !entry.Published.IsZero() {
+			if opts.relativeDates() {
+				meta = append(meta, entry.Published.In(location).Format("Jan 2, 2006"))
+			} else {
+				meta = append(meta, opts.formatPublished(entry.Published))
+			}
+		}
+		if entry.Source != "" {
+			meta = append(meta, markdownEscaper.Replace(entry.Source))
+		}
+		if len(meta) > 0 {
+			b.WriteString(" · " + strings.Join(meta, " · "))
+		}
+		b.WriteString("\n")
+		if opts.DescriptionLength > 0 && entry.Description != "" {
+			fmt.Fprintf(&b, "  %s\n", markdownEscaper.Replace(truncateRunes(entry.Description, opts.DescriptionLength)))
+		}
+	}
+	return b.String()
+}
+
+// sendCanvasToSlack replaces the content of channel's canvas with the digest.
+// The canvas is the one opts.Canvases records for the channel, or
+// opts.CanvasID, and is created the first time, or when it has been deleted,
+// with its id recorded in opts.Canvases for the next run.
+func sendCanvasToSlack(ctx context.Context, token, channel string, entries []FilteredEntry, opts slackOptions) error {
+	content := slackCanvasContent{Type: "markdown", Markdown: buildCanvasMarkdown(entries, opts, time.Now())}
+	logFields(fmt.Sprintf("Updating the Slack canvas of %s with %d DNS entries...", channel, len(entries)),
+		"notifier", "slack", "entry_count", len(entries))
+
+	id := opts.Canvases[channel]
+	if id == "" {
+		id = opts.CanvasID
+	}
+	if id != "" {
+		apiResp, err := callSlackAPI(ctx, token, "canvases.edit", slackCanvasEditRequest{
+			CanvasID: id,
+			Changes:  []slackCanvasChange{{Operation: "replace", DocumentContent: content}},
+		})
+		if err == nil {
+			infof("Successfully updated Slack canvas %s.\n", id)
+			return nil
+		}
+		if apiResp.Error != "canvas_not_found" {
+			return fmt.Errorf("error editing canvas %s: %w", id, err)
+		}
+		warnf("Warning: Slack canvas %s no longer exists, creating a new one\n", id)
+	}
+
+	apiResp, err := callSlackAPI(ctx, token, "conversations.canvases.create", slackChannelCanvasRequest{
+		ChannelID:       channel,
+		DocumentContent: content,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating channel canvas: %w", err)
+	}
+	if apiResp.CanvasID == "" {
+		return fmt.Errorf("error creating channel canvas: Slack API response has no canvas_id")
+	}
+	if opts.Canvases != nil {
+		opts.Canvases[channel] = apiResp.CanvasID
+	}
+	infof("Successfully created Slack canvas %s.\n", apiResp.CanvasID)
+	return nil
+}
+
+// postToSlackChannel posts the digest to channel via chat.postMessage, for
+// when the canvas can't be updated and there's no webhook to fall back to.
+func postToSlackChannel(ctx context.Context, token, channel string, entries []FilteredEntry, opts slackOptions) error {
+	chunks := splitSlackMessage(buildSlackMessage(entries, opts))
+	var errs []error
+	for i, chunk := range chunks {
+		if i > 0 {
+			if err := pause(ctx, opts.PostDelay); err != nil {
+				errs = append(errs, fmt.Errorf("message %d of %d: %w", i+1, len(chunks), err))
+				break
+			}
+		}
+		_, err := callSlackAPI(ctx, token, "chat.postMessage", slackPostRequest{
+			Channel:     channel,
+			Text:        chunk.Text,
+			Blocks:      chunk.Blocks,
+			Attachments: chunk.Attachments,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %d of %d: %w", i+1, len(chunks), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,136 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSlackAPIServer points the Slack Web API at a test server answering
+// each method with the response in responses, and returns the methods
+// called in order.
+func newSlackAPIServer(t *testing.T, responses map[string]string) *[]string {
+	t.Helper()
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		calls = append(calls, method)
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding %s payload: %v", method, err)
+		}
+		response, ok := responses[method]
+		if !ok {
+			t.Errorf("unexpected call of %s", method)
+		}
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+	client, baseURL := webhookClient, slackAPIBaseURL
+	webhookClient, slackAPIBaseURL = srv.Client(), srv.URL
+	t.Cleanup(func() { webhookClient, slackAPIBaseURL = client, baseURL })
+	return &calls
+}
+
+func TestBuildCanvasMarkdown(t *testing.T) {
+	entries := []FilteredEntry{{
+		Title:     "Registry [beta] raises *prices*",
+		Link:      "https://domainincite.com/1",
+		Source:    "Domain Incite",
+		Published: time.Date(2024, time.June, 1, 8, 0, 0, 0, time.UTC),
+	}}
+	opts := slackOptions{Messages: englishMessages, Location: time.UTC}
+	got := buildCanvasMarkdown(entries, opts, time.Date(2024, time.June, 2, 9, 30, 0, 0, time.UTC))
+
+	for _, want := range []string{
+		"# " + opts.headerText() + "\n",
+		"_Updated Jun 2, 2024 09:30 UTC_",
+		`- [Registry \[beta\] raises \*prices\*](https://domainincite.com/1) · Jun 1, 2024 · Domain Incite`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("markdown = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestSendCanvasToSlack(t *testing.T) {
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+
+	t.Run("creates then edits", func(t *testing.T) {
+		calls := newSlackAPIServer(t, map[string]string{
+			"conversations.canvases.create": `{"ok":true,"canvas_id":"F123"}`,
+			"canvases.edit":                 `{"ok":true}`,
+		})
+		opts := slackOptions{Messages: englishMessages, Canvases: map[string]string{}}
+		for range 2 {
+			if err := sendCanvasToSlack(t.Context(), "xoxb-test", "C123", entries, opts); err != nil {
+				t.Fatalf("sendCanvasToSlack: %v", err)
+			}
+		}
+		if opts.Canvases["C123"] != "F123" {
+			t.Errorf("canvases = %v, want the created canvas recorded", opts.Canvases)
+		}
+		if want := []string{"conversations.canvases.create", "canvases.edit"}; strings.Join(*calls, ",") != strings.Join(want, ",") {
+			t.Errorf("calls = %v, want %v", *calls, want)
+		}
+	})
+
+	t.Run("recreates a deleted canvas", func(t *testing.T) {
+		calls := newSlackAPIServer(t, map[string]string{
+			"canvases.edit":                 `{"ok":false,"error":"canvas_not_found"}`,
+			"conversations.canvases.create": `{"ok":true,"canvas_id":"F456"}`,
+		})
+		opts := slackOptions{Messages: englishMessages, Canvases: map[string]string{"C123": "F123"}}
+		if err := sendCanvasToSlack(t.Context(), "xoxb-test", "C123", entries, opts); err != nil {
+			t.Fatalf("sendCanvasToSlack: %v", err)
+		}
+		if opts.Canvases["C123"] != "F456" || len(*calls) != 2 {
+			t.Errorf("canvases = %v after %v, want the new canvas recorded", opts.Canvases, *calls)
+		}
+	})
+}
+
+func TestSlackNotifierCanvasFallsBackToMessages(t *testing.T) {
+	calls := newSlackAPIServer(t, map[string]string{
+		"conversations.canvases.create": `{"ok":false,"error":"free_team_not_allowed"}`,
+		"chat.postMessage":              `{"ok":true,"ts":"1715767200.000100"}`,
+	})
+	notifier := SlackNotifier{
+		Options: slackOptions{Messages: englishMessages, BotToken: "xoxb-test", Channel: "C123", Canvas: true},
+		Report:  newRunReport(time.Now()),
+	}
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+	if err := notifier.Send(t.Context(), entries); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(*calls) != 2 || (*calls)[1] != "chat.postMessage" {
+		t.Errorf("calls = %v, want the digest posted after the canvas failed", *calls)
+	}
+}
+
+func TestRunSlackCanvasRecordsCanvas(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "seen.json")
+	t.Setenv("STATE_FILE", state)
+	t.Setenv("SLACK_BOT_TOKEN", "xoxb-test")
+	t.Setenv("SLACK_CHANNEL", "C123")
+	t.Setenv("SLACK_CANVAS", "true")
+	newSlackAPIServer(t, map[string]string{"conversations.canvases.create": `{"ok":true,"canvas_id":"F123"}`})
+	feed := newFeedServer(t, "authors.xml")
+
+	cfg := Config{FeedURLs: []string{feed.URL}}
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	stored, err := loadState(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Canvases["C123"] != "F123" {
+		t.Errorf("canvases = %v, want the created canvas saved", stored.Canvases)
+	}
+}
@@ -0,0 +1,33 @@
+package rssnotify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// captureTimeFormat is sortable and filename-safe on all platforms.
+const captureTimeFormat = "20060102T150405.000000000Z"
+
+// capture saves data to a timestamped file in the CaptureDir set in ctx, if
+// any, so that the exact bytes seen during a run can be reused as test
+// fixtures. Failures are logged rather than interrupting the run.
+func capture(ctx context.Context, kind, ext string, data []byte) {
+	dir := settingsFrom(ctx).CaptureDir
+	if dir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		warnf("Warning: failed to create capture directory: %v\n", err)
+		return
+	}
+
+	path := filepath.Join(dir, kind+"-"+time.Now().UTC().Format(captureTimeFormat)+"."+ext)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		warnf("Warning: failed to write capture file: %v\n", err)
+		return
+	}
+	infof("Captured %s to %s\n", kind, path)
+}
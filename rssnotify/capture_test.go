@@ -0,0 +1,52 @@
+package rssnotify
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCaptureFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "captures")
+	ctx := withSettings(t.Context(), runSettings{CaptureDir: dir})
+	feed := newFeedServer(t, "feed.xml")
+	slack, _ := newSlackServer(t, http.StatusOK)
+
+	entries, err := fetchAndFilterRSSEntries(ctx, feed.Client(), feed.URL, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sendNotificationToSlack(ctx, slack.URL, entries, slackOptions{Messages: englishMessages}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	captured := make(map[string]string)
+	for _, f := range files {
+		kind, _, _ := strings.Cut(f.Name(), "-2") // The timestamp starts with the year
+		data, _ := os.ReadFile(filepath.Join(dir, f.Name()))
+		captured[kind+filepath.Ext(f.Name())] = string(data)
+	}
+	fixture, _ := os.ReadFile("testdata/feed.xml")
+	if captured["feed.xml"] != string(fixture) {
+		t.Errorf("captured files %v, want the feed body verbatim", files)
+	}
+	if !strings.Contains(captured["slack-payload.json"], "Registry raises prices") {
+		t.Errorf("captured files %v, want the Slack payload", files)
+	}
+}
+
+func TestCaptureDisabled(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir) // Where a relative or empty directory would write
+	capture(t.Context(), "feed", "xml", []byte("<rss/>"))
+	capture(withSettings(t.Context(), runSettings{}), "feed", "xml", []byte("<rss/>"))
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("captured %v without CAPTURE_DIR", files)
+	}
+}
@@ -0,0 +1,55 @@
+package rssnotify
+
+import "slices"
+
+// checkpoint records how far a run interrupted under CHECKPOINT_BATCH got
+// through delivering a feed's entries, so the next run resumes after it.
+type checkpoint struct {
+	Index int    `json:"index"` // Position of the last delivered entry among the feed's matched entries, 0 for the first
+	Key   string `json:"key"`   // That entry's key in the seen state
+}
+
+// recordCheckpoints records the last entry of each feed in delivered, each
+// locating itself in fetched, the run's matched entries in feed order.
+func recordCheckpoints(checkpoints map[string]checkpoint, delivered, fetched []FilteredEntry, key func(FilteredEntry) string) {
+	for _, entry := range delivered {
+		k := key(entry)
+		index := slices.IndexFunc(fetched, func(other FilteredEntry) bool { return other.FeedURL == entry.FeedURL && key(other) == k })
+		first := slices.IndexFunc(fetched, func(other FilteredEntry) bool { return other.FeedURL == entry.FeedURL })
+		checkpoints[entry.FeedURL] = checkpoint{Index: index - first, Key: k}
+	}
+}
+
+// resumeAfter drops the entries of each feed up to and including the one
+// its checkpoint names, as the interrupted run delivered those. This relies
+// on the feed listing its items in the same order as last time. A feed whose
+// checkpoint entry is no longer listed is kept whole, leaving it to the seen
+// keys.
+func resumeAfter(entries []FilteredEntry, checkpoints map[string]checkpoint, key func(FilteredEntry) string) []FilteredEntry {
+	done := make(map[string]bool) // Feeds whose checkpoint entry has been passed
+	listed, found := make(map[string]bool), make(map[string]bool)
+	for _, entry := range entries {
+		listed[entry.FeedURL] = true
+		if cp, ok := checkpoints[entry.FeedURL]; ok && key(entry) == cp.Key {
+			found[entry.FeedURL] = true
+		}
+	}
+	for feedURL, cp := range checkpoints {
+		if listed[feedURL] && !found[feedURL] {
+			warnf("Warning: the checkpoint entry %q (position %d) of %s is no longer listed, so the feed isn't resumed\n", cp.Key, cp.Index, feedURL)
+			done[feedURL] = true
+		}
+	}
+
+	var rest []FilteredEntry
+	for _, entry := range entries {
+		cp, ok := checkpoints[entry.FeedURL]
+		switch {
+		case !ok || done[entry.FeedURL]:
+			rest = append(rest, entry)
+		case key(entry) == cp.Key:
+			done[entry.FeedURL] = true
+		}
+	}
+	return rest
+}
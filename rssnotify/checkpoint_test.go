@@ -0,0 +1,86 @@
+package rssnotify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResumeAfter(t *testing.T) {
+	key := func(entry FilteredEntry) string { return entry.GUID }
+	entry := func(feed, guid string) FilteredEntry { return FilteredEntry{GUID: guid, FeedURL: feed} }
+	entries := []FilteredEntry{entry("a", "a1"), entry("a", "a2"), entry("a", "a3"), entry("b", "b1"), entry("b", "b2")}
+
+	checkpoints := make(map[string]checkpoint)
+	recordCheckpoints(checkpoints, entries[:2], entries, key)
+	if got := checkpoints["a"]; got != (checkpoint{Index: 1, Key: "a2"}) {
+		t.Errorf("checkpoint = %+v, want the second entry of feed a", got)
+	}
+
+	checkpoints["b"] = checkpoint{Index: 0, Key: "gone"} // No longer listed
+	var got []string
+	for _, entry := range resumeAfter(entries, checkpoints, key) {
+		got = append(got, entry.GUID)
+	}
+	if want := []string{"a3", "b1", "b2"}; !slices.Equal(got, want) {
+		t.Errorf("resumed = %v, want %v", got, want)
+	}
+}
+
+func TestRunCheckpointResume(t *testing.T) {
+	state := t.TempDir() + "/seen.json"
+	t.Setenv("STATE_FILE", state)
+	t.Setenv("CHECKPOINT_BATCH", "1")
+	delay := retryBaseDelay
+	retryBaseDelay = 0
+	t.Cleanup(func() { retryBaseDelay = delay })
+	feed := newFeedServer(t, "dateless.xml")
+
+	// The first run is interrupted delivering the second entry.
+	failing := true
+	var delivered []string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if failing && strings.Contains(string(data), "Nameserver maintenance") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, title := range []string{"Transfer lock changes", "Nameserver maintenance", "Welcome"} {
+			if strings.Contains(string(data), title) {
+				delivered = append(delivered, title)
+			}
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer slack.Close()
+	cfg := Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL}
+
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err == nil {
+		t.Fatal("expected the interrupted run to fail")
+	}
+	stored, err := loadState(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stored.Checkpoints[feed.URL]; got != (checkpoint{Index: 0, Key: "note-3"}) || !stored.Seen.has(feed.URL, "note-3") {
+		t.Errorf("checkpoint = %+v (seen %v), want the first entry recorded", got, stored.Seen)
+	}
+
+	failing = false
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+		t.Fatalf("resumed run: %v", err)
+	}
+	if want := []string{"Transfer lock changes", "Nameserver maintenance", "Welcome"}; !slices.Equal(delivered, want) {
+		t.Errorf("delivered = %v, want each entry once", delivered)
+	}
+	if stored, err = loadState(state, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Checkpoints) != 0 {
+		t.Errorf("checkpoints = %v, want them cleared on completion", stored.Checkpoints)
+	}
+}
@@ -0,0 +1,386 @@
+package rssnotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings that can be given in a config file as well as
+// the environment. Environment variables override file values, so a shared
+// file can be adjusted per run.
+//
+// Settings without a field can be set through Env, which maps environment
+// variable names to values, e.g. {"SLACK_LAYOUT": "summary-first"}.
+type Config struct {
+	FeedURLs             []string                `json:"feeds"`                   // RSS_FEED_URL
+	Categories           []string                `json:"categories"`              // RSS_FILTER_CATEGORIES
+	Keywords             []string                `json:"keywords"`                // RSS_FILTER_KEYWORDS
+	Notifier             string                  `json:"notifier"`                // NOTIFIER
+	SlackWebhookURL      string                  `json:"slack_webhook_url"`       // SLACK_WEBHOOK_URL
+	DiscordWebhookURL    string                  `json:"discord_webhook_url"`     // DISCORD_WEBHOOK_URL
+	TeamsWebhookURL      string                  `json:"teams_webhook_url"`       // TEAMS_WEBHOOK_URL
+	GoogleChatWebhookURL string                  `json:"google_chat_webhook_url"` // GOOGLE_CHAT_WEBHOOK_URL
+	GenericWebhookURL    string                  `json:"generic_webhook_url"`     // GENERIC_WEBHOOK_URL
+	TimeoutSeconds       int                     `json:"timeout_seconds"`         // HTTP_TIMEOUT_SECONDS
+	FeedSettings         map[string]feedSettings `json:"feed_settings"`           // Per feed URL; config file only
+	Env                  map[string]string       `json:"env"`
+	Render               bool                    `json:"-"` // The -render flag
+}
+
+// feedSettings overrides the global HTTP settings for a single feed, as
+// feeds differ widely in how slow and unreliable they are. Zero fields keep
+// HTTP_TIMEOUT_SECONDS and HTTP_MAX_RETRIES.
+type feedSettings struct {
+	TimeoutSeconds int `json:"timeout_seconds"` // Bounds each request for the feed
+	MaxRetries     int `json:"max_retries"`     // Attempts made for the feed
+}
+
+// loadConfig reads the JSON config file at path and resolves it against the
+// environment. An empty path yields the configuration from the environment
+// alone.
+func loadConfig(path string) (Config, error) {
+	var cfg Config
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("error reading config file: %w", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields() // Catch misspelled keys
+		if err := decoder.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	}
+
+	if value := cfg.getenv("RSS_FEED_URL"); value != "" {
+		cfg.FeedURLs = splitList(value)
+	} else {
+		// Blank file entries would otherwise be fetched as feeds.
+		var feeds []string
+		for _, feed := range cfg.FeedURLs {
+			if feed = strings.TrimSpace(feed); feed != "" {
+				feeds = append(feeds, feed)
+			}
+		}
+		cfg.FeedURLs = feeds
+	}
+	if value := cfg.getenv("RSS_FILTER_CATEGORIES"); value != "" || len(cfg.Categories) == 0 {
+		cfg.Categories = parseCategories(value)
+	}
+	if value := cfg.getenv("RSS_FILTER_KEYWORDS"); value != "" {
+		cfg.Keywords = splitList(value)
+	}
+	cfg.overrideString(&cfg.Notifier, "NOTIFIER")
+	cfg.overrideString(&cfg.SlackWebhookURL, "SLACK_WEBHOOK_URL")
+	cfg.overrideString(&cfg.DiscordWebhookURL, "DISCORD_WEBHOOK_URL")
+	cfg.overrideString(&cfg.TeamsWebhookURL, "TEAMS_WEBHOOK_URL")
+	cfg.overrideString(&cfg.GoogleChatWebhookURL, "GOOGLE_CHAT_WEBHOOK_URL")
+	cfg.overrideString(&cfg.GenericWebhookURL, "GENERIC_WEBHOOK_URL")
+	if timeout := cfg.envSeconds("HTTP_TIMEOUT_SECONDS"); timeout > 0 {
+		cfg.TimeoutSeconds = int(timeout.Seconds())
+	}
+	return cfg, nil
+}
+
+// overrideString replaces *field with the named setting when it is set and
+// not blank.
+func (c Config) overrideString(field *string, name string) {
+	if value := strings.TrimSpace(c.getenv(name)); value != "" {
+		*field = value
+	}
+}
+
+// getenv returns the named setting: the environment variable when it is set,
+// otherwise the value given in Env.
+func (c Config) getenv(name string) string {
+	value, _ := c.lookupEnv(name)
+	return value
+}
+
+// lookupEnv is like getenv, but also reports whether the setting is set at
+// all, so an explicitly empty value can be told apart from an unset one.
+func (c Config) lookupEnv(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := c.Env[name]
+	return value, ok
+}
+
+// envBool reports whether the named setting is a truthy value (e.g. "true",
+// "1"). Unset or unparseable values return fallback.
+func (c Config) envBool(name string, fallback bool) bool {
+	value := strings.TrimSpace(c.getenv(name))
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		warnf("Warning: invalid boolean value %q for %s, using default %t\n", value, name, fallback)
+		return fallback
+	}
+	return b
+}
+
+// envSeconds returns the named setting as a duration in whole seconds.
+// Unset, unparseable and non-positive values return 0, so callers keep their
+// defaults.
+func (c Config) envSeconds(name string) time.Duration {
+	value := strings.TrimSpace(c.getenv(name))
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		warnf("Warning: invalid value %q for %s, expected a positive number of seconds; using defaults\n", value, name)
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+// envInt returns the named setting parsed as an integer. Unset or
+// unparseable values return fallback.
+func (c Config) envInt(name string, fallback int) int {
+	value := strings.TrimSpace(c.getenv(name))
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		warnf("Warning: invalid integer value %q for %s, using default %d\n", value, name, fallback)
+		return fallback
+	}
+	return n
+}
+
+// runConfig is a Config with every setting a run reads resolved into typed
+// values. Config.resolve builds it once at startup, so invalid values are
+// reported before any feed is fetched rather than midway through a run.
+type runConfig struct {
+	Config
+
+	Filters       filterOptions // How feed items are filtered
+	Slack         slackOptions  // How the digest is rendered and delivered
+	HTTP          runSettings   // Retries, headers and timeouts; Main adds the counter
+	MinTLSVersion uint16        // RSS_MIN_TLS_VERSION; 0 keeps Go's default
+	RunTimeout    time.Duration // RUN_TIMEOUT_SECONDS; 0 disables
+	SoftDeadline  time.Duration // SOFT_DEADLINE_SECONDS; 0 disables
+	Concurrency   int           // FETCH_CONCURRENCY
+
+	Ascending       bool             // SORT_ORDER=asc
+	StateFile       string           // STATE_FILE, defaulting to defaultStateFile
+	SeenKey         string           // SEEN_KEY, one of the seenKey* constants
+	OverlapCheck    bool             // STATE_OVERLAP_CHECK, warn when a feed shares no entries with the state
+	DedupWindow     time.Duration    // DEDUP_WINDOW_DAYS; 0 keeps seen entries forever
+	DigestMode      string           // DIGEST_MODE, one of the digest* constants
+	DailySendAt     string           // DIGEST_SEND_AT under DIGEST_MODE=daily-fresh, a daily HH:MM
+	CheckpointBatch int              // CHECKPOINT_BATCH; 0 delivers everything at once
+	DedupScope      string           // DEDUP_SCOPE, one of the dedupScope* constants
+	Flood           floodGuard       // GUARD_AGAINST_FLOOD and its settings
+	SinceLastRun    bool             // SINCE_LAST_RUN
+	DatelessOrder   bool             // DATELESS_FEED_ORDER, rank undated feeds by position
+	FirstRunSend    bool             // FIRST_RUN_SEND
+	VerifyLinks     bool             // VERIFY_LINKS
+	KeepUnreachable bool             // VERIFY_LINKS_KEEP_UNREACHABLE
+	MaxEntries      int              // MAX_ENTRIES; 0 disables
+	ArchiveDir      string           // ARCHIVE_DIR, if set
+	Archive         archiveRetention // ARCHIVE_MAX_FILES and ARCHIVE_MAX_AGE_DAYS
+	Output          string           // OUTPUT, one of the output* constants
+	OutputFile      string           // OUTPUT_FILE, defaulting to defaultOutputFile
+	ReportFile      string           // RUN_REPORT_FILE, if set
+}
+
+// resolve parses the settings of c into a runConfig, returning an error for
+// values that can't be used. Values with a sensible fallback are warned about
+// and replaced instead, as by the parse functions.
+func (c Config) resolve() (runConfig, error) {
+	rc := runConfig{Config: c}
+	var err error
+
+	categories := c.Categories
+	if len(categories) == 0 {
+		categories = defaultCategories
+	}
+	rc.Filters = filterOptions{
+		Categories:            categories,
+		CategoryMatch:         parseCategoryMatch(c.getenv("CATEGORY_MATCH_MODE")),
+		MinCategoryMatches:    c.envInt("RSS_MIN_CATEGORY_MATCHES", 1),
+		RequireEnclosureTypes: splitList(c.getenv("RSS_REQUIRE_ENCLOSURE_TYPE")),
+		ExcludeEnclosureTypes: splitList(c.getenv("RSS_EXCLUDE_ENCLOSURE_TYPE")),
+		MaxEnclosureBytes:     int64(c.envInt("RSS_MAX_ENCLOSURE_BYTES", 0)),
+		MinContentLength:      c.envInt("RSS_MIN_CONTENT_LENGTH", 0),
+		AllowEmptyContent:     c.envBool("RSS_ALLOW_EMPTY_CONTENT", false),
+		ForceInclude:          make(map[string]bool),
+		RejectDoctype:         c.envBool("RSS_REJECT_DOCTYPE", false),
+		MaxAge:                time.Duration(c.envInt("MAX_AGE_DAYS", 0)) * 24 * time.Hour,
+		DropUndated:           c.envBool("MAX_AGE_DROP_UNDATED", false),
+		AllowDomains:          splitList(strings.ToLower(c.getenv("LINK_ALLOW_DOMAINS"))),
+		BlockDomains:          splitList(strings.ToLower(c.getenv("LINK_BLOCK_DOMAINS"))),
+		LinkNormalization:     parseLinkNormalization(c.getenv("LINK_NORMALIZATION")),
+		DedupField:            parseDedupField(c.getenv("DEDUP_FIELD")),
+	}
+	if rc.Filters.MinCategoryMatches > len(rc.Filters.Categories) {
+		warnf("Warning: RSS_MIN_CATEGORY_MATCHES is %d but only %d categories are configured, so no item matches by category\n", rc.Filters.MinCategoryMatches, len(rc.Filters.Categories))
+	}
+	for _, id := range splitList(c.getenv("RSS_FORCE_INCLUDE_GUIDS")) {
+		rc.Filters.ForceInclude[id] = true
+	}
+	for _, keyword := range c.Keywords {
+		rc.Filters.Keywords = append(rc.Filters.Keywords, strings.ToLower(keyword))
+	}
+	for _, cat := range splitList(c.getenv("RSS_PRIORITY_CATEGORIES")) {
+		rc.Filters.PriorityCategories = append(rc.Filters.PriorityCategories, strings.ToLower(cat))
+	}
+	if rc.Filters.PriorityPattern, err = compilePattern("RSS_PRIORITY_REGEX", c.getenv("RSS_PRIORITY_REGEX")); err != nil {
+		return rc, err
+	}
+	if rc.Filters.TitleExclude, err = compilePattern("RSS_TITLE_EXCLUDE_REGEX", c.getenv("RSS_TITLE_EXCLUDE_REGEX")); err != nil {
+		return rc, err
+	}
+
+	feedAuth, err := feedAuthorization(c.getenv("RSS_BASIC_AUTH"), strings.TrimSpace(c.getenv("RSS_BEARER_TOKEN")))
+	if err != nil {
+		return rc, err
+	}
+	rc.HTTP = runSettings{
+		MaxRetries:        max(c.envInt("HTTP_MAX_RETRIES", defaultMaxRetries), 1),
+		UserAgent:         c.getenv("HTTP_USER_AGENT"),
+		AcceptHeader:      c.getenv("RSS_ACCEPT_HEADER"),
+		FeedAuthorization: feedAuth,
+		WebhookTimeout:    time.Duration(c.TimeoutSeconds) * time.Second,
+		CaptureDir:        c.getenv("CAPTURE_DIR"),
+	}
+	for feedURL, settings := range c.FeedSettings {
+		if settings.TimeoutSeconds < 0 || settings.MaxRetries < 0 {
+			return rc, fmt.Errorf("invalid feed_settings for %s: timeout_seconds and max_retries must not be negative", feedURL)
+		}
+		if !slices.Contains(c.FeedURLs, feedURL) {
+			warnf("Warning: feed_settings names %s, which isn't among the configured feeds\n", feedURL)
+		}
+	}
+	if rc.MinTLSVersion, err = parseTLSVersion(c.getenv("RSS_MIN_TLS_VERSION")); err != nil {
+		return rc, fmt.Errorf("invalid RSS_MIN_TLS_VERSION: %w", err)
+	}
+	rc.RunTimeout = c.envSeconds("RUN_TIMEOUT_SECONDS")
+	rc.SoftDeadline = c.envSeconds("SOFT_DEADLINE_SECONDS")
+	if rc.SoftDeadline > 0 && rc.RunTimeout > 0 && rc.SoftDeadline >= rc.RunTimeout {
+		warnf("Warning: SOFT_DEADLINE_SECONDS (%s) isn't before RUN_TIMEOUT_SECONDS (%s), so the run is cancelled before it trips\n", rc.SoftDeadline, rc.RunTimeout)
+	}
+	rc.Concurrency = c.envInt("FETCH_CONCURRENCY", defaultFetchConcurrency)
+
+	rc.Ascending = parseSortOrder(c.getenv("SORT_ORDER"))
+	rc.StateFile = c.getenv("STATE_FILE")
+	if rc.StateFile == "" {
+		rc.StateFile = defaultStateFile
+	}
+	rc.SeenKey = parseSeenKey(c.getenv("SEEN_KEY"))
+	if err := checkSeenKey(rc.SeenKey, rc.Filters.DedupField); err != nil {
+		return rc, err
+	}
+	rc.OverlapCheck = c.envBool("STATE_OVERLAP_CHECK", false)
+	// Seen entries older than the window are forgotten, so the age filter
+	// must drop them too or they would be sent again.
+	rc.DedupWindow = time.Duration(max(c.envInt("DEDUP_WINDOW_DAYS", 0), 0)) * 24 * time.Hour
+	if rc.DedupWindow > 0 && (rc.Filters.MaxAge <= 0 || rc.Filters.MaxAge > rc.DedupWindow) {
+		rc.Filters.MaxAge = rc.DedupWindow
+	}
+	rc.SinceLastRun = c.envBool("SINCE_LAST_RUN", false)
+	rc.DigestMode = parseDigestMode(c.getenv("DIGEST_MODE"))
+	rc.DedupScope = parseDedupScope(c.getenv("DEDUP_SCOPE"))
+	if rc.DedupScope == dedupScopeSink && rc.DigestMode == digestDiff {
+		warnf("Warning: DEDUP_SCOPE=%s has no effect with DIGEST_MODE=%s, which doesn't check seen entries\n", dedupScopeSink, digestDiff)
+		rc.DedupScope = dedupScopeGlobal
+	}
+	rc.CheckpointBatch = max(c.envInt("CHECKPOINT_BATCH", 0), 0)
+	if rc.CheckpointBatch > 0 && rc.DigestMode != digestNew {
+		warnf("Warning: CHECKPOINT_BATCH has no effect with DIGEST_MODE=%s, which posts the digest at once\n", rc.DigestMode)
+		rc.CheckpointBatch = 0
+	}
+	rc.Flood = floodGuard{
+		Enabled:     c.envBool("GUARD_AGAINST_FLOOD", false),
+		Factor:      max(c.envInt("FLOOD_FACTOR", defaultFloodFactor), 1),
+		MinEntries:  c.envInt("FLOOD_MIN_ENTRIES", defaultFloodMinEntries),
+		PendingFile: c.getenv("PENDING_FILE"),
+	}
+	if rc.Flood.PendingFile == "" {
+		rc.Flood.PendingFile = defaultPendingFile
+	}
+	rc.DatelessOrder = c.envBool("DATELESS_FEED_ORDER", false)
+	rc.FirstRunSend = c.envBool("FIRST_RUN_SEND", true)
+	rc.VerifyLinks = c.envBool("VERIFY_LINKS", false)
+	rc.KeepUnreachable = c.envBool("VERIFY_LINKS_KEEP_UNREACHABLE", true)
+	rc.MaxEntries = c.envInt("MAX_ENTRIES", 0)
+	rc.ArchiveDir = c.getenv("ARCHIVE_DIR")
+	rc.Archive = archiveRetention{
+		MaxFiles: c.envInt("ARCHIVE_MAX_FILES", 0),
+		MaxAge:   time.Duration(c.envInt("ARCHIVE_MAX_AGE_DAYS", 0)) * 24 * time.Hour,
+	}
+	rc.Output = parseOutput(c.getenv("OUTPUT"))
+	rc.OutputFile = c.getenv("OUTPUT_FILE")
+	if rc.OutputFile == "" {
+		rc.OutputFile = defaultOutputFile
+	}
+	rc.ReportFile = c.getenv("RUN_REPORT_FILE")
+
+	msgs, err := loadMessages(c.getenv("MESSAGES_FILE"), c.getenv("LOCALE"))
+	if err != nil {
+		warnf("Warning: %v, using %s messages\n", err, defaultLocale)
+	}
+	if label := strings.TrimSpace(c.getenv("SLACK_HEADER_TEXT")); label != "" {
+		msgs.Label = label
+	}
+	if emoji, ok := c.lookupEnv("SLACK_HEADER_EMOJI"); ok {
+		msgs.Emoji = strings.TrimSpace(emoji)
+	}
+	rc.Slack = slackOptions{
+		ShowDiscussLink:   c.envBool("SHOW_DISCUSS_LINK", false),
+		Messages:          msgs,
+		Layout:            parseLayout(c.getenv("SLACK_LAYOUT")),
+		ShowEnclosureSize: c.envBool("SHOW_ENCLOSURE_SIZE", false),
+		CategoryEmoji:     parseMapping(c.getenv("CATEGORY_EMOJI"), strings.ToLower),
+		CategoryColors:    parseColors(c.getenv("CATEGORY_COLORS")),
+		ShowFavicon:       c.envBool("SLACK_SHOW_FAVICON", false),
+		RichEntries:       c.envBool("SLACK_RICH_ENTRIES", false),
+		PriorityMention:   c.getenv("SLACK_PRIORITY_MENTION"),
+		NotifyOnEmpty:     c.envBool("NOTIFY_ON_EMPTY", false),
+		DryRun:            c.envBool("DRY_RUN", false),
+		Render:            c.Render,
+		Location:          displayLocation(c.getenv("DISPLAY_TIMEZONE")),
+		DescriptionLength: c.envInt("DESCRIPTION_MAX_LENGTH", defaultDescriptionLength),
+		PostDelay:         time.Duration(c.envInt("SLACK_POST_DELAY_MS", 0)) * time.Millisecond,
+		BotToken:          c.getenv("SLACK_BOT_TOKEN"),
+		Channel:           c.getenv("SLACK_CHANNEL"),
+		SendAt:            c.getenv("DIGEST_SEND_AT"),
+		Threaded:          c.envBool("SLACK_THREADED", false),
+		Canvas:            c.envBool("SLACK_CANVAS", false),
+		CanvasID:          c.getenv("SLACK_CANVAS_ID"),
+		Format:            parseSlackFormat(c.getenv("SLACK_FORMAT")),
+		WorkflowVariables: parseWorkflowVariables(c.getenv("SLACK_WORKFLOW_VARIABLES")),
+	}
+	rc.Slack.PublishedFormat = parsePublishedFormat(c.getenv("PUBLISHED_DISPLAY_FORMAT"), rc.Slack.Location)
+	if rc.DigestMode == digestDailyFresh {
+		// The run posts the digest once due, rather than Slack scheduling it.
+		if _, err := time.Parse("15:04", strings.TrimSpace(rc.Slack.SendAt)); err != nil {
+			return rc, fmt.Errorf("DIGEST_MODE=%s requires DIGEST_SEND_AT as a daily HH:MM time, got %q", digestDailyFresh, rc.Slack.SendAt)
+		}
+		rc.DailySendAt, rc.Slack.SendAt = strings.TrimSpace(rc.Slack.SendAt), ""
+	}
+	if rc.Slack.SendAt != "" {
+		if _, err := parseSendAt(rc.Slack.SendAt, time.Now().In(rc.Slack.Location)); err != nil {
+			return rc, err
+		}
+	}
+	if rc.Slack.Render && !rc.Slack.DryRun {
+		warnf("Warning: -render only applies with DRY_RUN=true, ignoring it\n")
+	}
+	if layout := c.getenv("SLACK_HEADER_DATE_FORMAT"); layout != "" {
+		rc.Slack.HeaderDate = time.Now().In(rc.Slack.Location).Format(layout)
+	}
+	return rc, nil
+}
@@ -0,0 +1,209 @@
+package rssnotify
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+  "feeds": ["https://example.com/a.xml", "https://example.com/b.xml"],
+  "categories": ["dns", "security"],
+  "notifier": "discord",
+  "discord_webhook_url": "https://discord.example/file",
+  "timeout_seconds": 45,
+  "feed_settings": {"https://example.com/b.xml": {"timeout_seconds": 90, "max_retries": 5}},
+  "env": {"RSS_NOTIFICATIONS_TEST_SETTING": "from-file"}
+}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DISCORD_WEBHOOK_URL", "https://discord.example/env")
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	if want := []string{"https://example.com/a.xml", "https://example.com/b.xml"}; !reflect.DeepEqual(cfg.FeedURLs, want) {
+		t.Errorf("feeds = %v, want %v", cfg.FeedURLs, want)
+	}
+	if want := []string{"dns", "security"}; !reflect.DeepEqual(cfg.Categories, want) {
+		t.Errorf("categories = %v, want %v", cfg.Categories, want)
+	}
+	if cfg.Notifier != "discord" || cfg.TimeoutSeconds != 45 {
+		t.Errorf("notifier = %q, timeout = %d", cfg.Notifier, cfg.TimeoutSeconds)
+	}
+	if got := cfg.FeedSettings["https://example.com/b.xml"]; got != (feedSettings{TimeoutSeconds: 90, MaxRetries: 5}) {
+		t.Errorf("feed settings = %+v", got)
+	}
+	if cfg.DiscordWebhookURL != "https://discord.example/env" {
+		t.Errorf("discord webhook = %q, want the environment to override the file", cfg.DiscordWebhookURL)
+	}
+	if got := cfg.getenv("RSS_NOTIFICATIONS_TEST_SETTING"); got != "from-file" {
+		t.Errorf("env entry not applied, got %q", got)
+	}
+	if _, ok := os.LookupEnv("RSS_NOTIFICATIONS_TEST_SETTING"); ok {
+		t.Error("env entry leaked into the process environment")
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := loadConfig("")
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Categories, defaultCategories) {
+		t.Errorf("categories = %v, want the defaults", cfg.Categories)
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"feed": ["https://example.com/a.xml"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Fatal("expected an error for a misspelled key")
+	}
+}
+
+func TestConfigSettings(t *testing.T) {
+	cfg := Config{Env: map[string]string{
+		"FETCH_CONCURRENCY":   "8",
+		"DRY_RUN":             "true",
+		"RUN_TIMEOUT_SECONDS": "90",
+		"SORT_ORDER":          "asc",
+	}}
+	for _, name := range []string{"DRY_RUN", "RUN_TIMEOUT_SECONDS", "SORT_ORDER", "SLACK_HEADER_EMOJI", "MAX_AGE_DAYS"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	t.Setenv("FETCH_CONCURRENCY", "2")
+
+	if got := cfg.envInt("FETCH_CONCURRENCY", defaultFetchConcurrency); got != 2 {
+		t.Errorf("FETCH_CONCURRENCY = %d, want the environment to override Env", got)
+	}
+	if !cfg.envBool("DRY_RUN", false) {
+		t.Error("DRY_RUN from Env not applied")
+	}
+	if got := cfg.envSeconds("RUN_TIMEOUT_SECONDS"); got != 90*time.Second {
+		t.Errorf("RUN_TIMEOUT_SECONDS = %v, want 90s", got)
+	}
+	if got := cfg.getenv("SORT_ORDER"); got != "asc" {
+		t.Errorf("SORT_ORDER = %q, want asc from Env", got)
+	}
+	cfg.Env["SLACK_HEADER_EMOJI"] = ""
+	if value, ok := cfg.lookupEnv("SLACK_HEADER_EMOJI"); !ok || value != "" {
+		t.Errorf("SLACK_HEADER_EMOJI = %q, %v; want an explicitly empty value from Env", value, ok)
+	}
+	if got := cfg.envInt("MAX_AGE_DAYS", 7); got != 7 {
+		t.Errorf("unset MAX_AGE_DAYS = %d, want the fallback", got)
+	}
+}
+
+func TestRunNoFeeds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"feeds": ["", "  "]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		feedEnv string
+	}{
+		{"blank config feeds", path, ""},
+		{"separators only", "", " , ,"},
+	}
+	for _, tt := range tests {
+		t.Setenv("RSS_FEED_URL", tt.feedEnv)
+		cfg, err := loadConfig(tt.path)
+		if err != nil {
+			t.Fatalf("%s: loadConfig: %v", tt.name, err)
+		}
+		if len(cfg.FeedURLs) != 0 {
+			t.Errorf("%s: feeds = %q, want none", tt.name, cfg.FeedURLs)
+		}
+		if err := run(t.Context(), http.DefaultClient, resolveConfig(t, cfg), newRunReport(time.Now())); !errors.Is(err, errNoFeeds) {
+			t.Errorf("%s: run error = %v, want errNoFeeds", tt.name, err)
+		}
+	}
+}
+
+func TestResolveRejectsNegativeFeedSettings(t *testing.T) {
+	cfg := Config{FeedURLs: []string{"https://example.com/a.xml"}, FeedSettings: map[string]feedSettings{"https://example.com/a.xml": {MaxRetries: -1}}}
+	if _, err := cfg.resolve(); err == nil {
+		t.Error("expected an error for a negative max_retries")
+	}
+}
+
+func TestResolveRejectsConflictingSeenKey(t *testing.T) {
+	tests := []struct {
+		seenKey, dedupField string
+		wantErr             bool
+	}{
+		{"", "title", false},
+		{"guid", "dc:identifier", false},
+		{"link", "", false},
+		{"link", "link", false},
+		{"hash", "guid", false},
+		{"link", "title", true},
+		{"hash", "link", true},
+		{"hash", "source/@url", true},
+	}
+	for _, tt := range tests {
+		cfg := Config{Env: map[string]string{"SEEN_KEY": tt.seenKey, "DEDUP_FIELD": tt.dedupField}}
+		if _, err := cfg.resolve(); (err != nil) != tt.wantErr {
+			t.Errorf("SEEN_KEY=%q DEDUP_FIELD=%q: err = %v, want error %v", tt.seenKey, tt.dedupField, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveConfig(t *testing.T) {
+	cfg := Config{Env: map[string]string{
+		"STATE_FILE":          "/tmp/state.json",
+		"SEEN_KEY":            "hash",
+		"MAX_ENTRIES":         "5",
+		"SORT_ORDER":          "asc",
+		"SLACK_LAYOUT":        "summary-last",
+		"HTTP_MAX_RETRIES":    "0",
+		"OUTPUT":              "html",
+		"RSS_MIN_TLS_VERSION": "1.2",
+	}}
+	rc, err := cfg.resolve()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if rc.StateFile != "/tmp/state.json" || rc.SeenKey != seenKeyHash || rc.MaxEntries != 5 || !rc.Ascending {
+		t.Errorf("state settings = %q %q %d %v", rc.StateFile, rc.SeenKey, rc.MaxEntries, rc.Ascending)
+	}
+	if rc.Slack.Layout != layoutSummaryLast || rc.HTTP.MaxRetries != 1 || rc.Output != outputHTML || rc.OutputFile != defaultOutputFile {
+		t.Errorf("layout = %q, retries = %d, output = %q %q", rc.Slack.Layout, rc.HTTP.MaxRetries, rc.Output, rc.OutputFile)
+	}
+	if !reflect.DeepEqual(rc.Filters.Categories, defaultCategories) {
+		t.Errorf("categories = %v, want the defaults", rc.Filters.Categories)
+	}
+
+	if rc.Slack.PublishedFormat != publishedRelative {
+		t.Errorf("published format = %q, want %q", rc.Slack.PublishedFormat, publishedRelative)
+	}
+	cfg.Env["PUBLISHED_DISPLAY_FORMAT"] = "yyyy-mm-dd" // Rejected at startup
+	if rc, err := cfg.resolve(); err != nil || rc.Slack.PublishedFormat != publishedRelative {
+		t.Errorf("invalid PUBLISHED_DISPLAY_FORMAT: format = %q, err = %v", rc.Slack.PublishedFormat, err)
+	}
+	cfg.Env["PUBLISHED_DISPLAY_FORMAT"] = "2006-01-02"
+	if rc, err := cfg.resolve(); err != nil || rc.Slack.PublishedFormat != "2006-01-02" {
+		t.Errorf("PUBLISHED_DISPLAY_FORMAT layout: format = %q, err = %v", rc.Slack.PublishedFormat, err)
+	}
+
+	cfg.Env["RSS_TITLE_EXCLUDE_REGEX"] = "("
+	if _, err := cfg.resolve(); err == nil {
+		t.Error("expected an error for an invalid RSS_TITLE_EXCLUDE_REGEX")
+	}
+}
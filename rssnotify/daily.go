@@ -0,0 +1,52 @@
+package rssnotify
+
+import (
+	"slices"
+	"time"
+)
+
+// dailyDigest accumulates the entries matched between two daily
+// DIGEST_MODE=daily-fresh digests.
+type dailyDigest struct {
+	Entries  []FilteredEntry `json:"entries,omitempty"`  // Matched since the last digest, each once
+	LastSent time.Time       `json:"last_sent,omitzero"` // The DIGEST_SEND_AT time of the last digest
+}
+
+// add appends the entries not accumulated yet and returns how many it added.
+func (d *dailyDigest) add(entries []FilteredEntry, key func(FilteredEntry) string) int {
+	have := make(seenEntries)
+	for _, entry := range d.Entries {
+		have.add(entry.FeedURL, key(entry))
+	}
+	added := 0
+	for _, entry := range entries {
+		if have.has(entry.FeedURL, key(entry)) {
+			continue
+		}
+		have.add(entry.FeedURL, key(entry))
+		d.Entries = append(d.Entries, entry)
+		added++
+	}
+	return added
+}
+
+// lastSendAt returns the latest time at the daily "HH:MM" sendAt that isn't
+// after now, in now's location, which DIGEST_MODE=daily-fresh posts the
+// digest of. A run at or after it posts the digest unless one was already
+// posted for it.
+func lastSendAt(sendAt string, now time.Time) (time.Time, error) {
+	next, err := parseSendAt(sendAt, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return next.AddDate(0, 0, -1), nil
+}
+
+// sortDaily orders accumulated entries as a run would: grouped by feed in
+// the order of feedURLs, then as sortEntries orders them.
+func sortDaily(entries []FilteredEntry, feedURLs []string, ascending bool) {
+	sortEntries(entries, ascending)
+	slices.SortStableFunc(entries, func(a, b FilteredEntry) int {
+		return slices.Index(feedURLs, a.FeedURL) - slices.Index(feedURLs, b.FeedURL)
+	})
+}
@@ -0,0 +1,94 @@
+package rssnotify
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLastSendAt(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"before today's time", time.Date(2024, time.June, 1, 17, 59, 0, 0, time.UTC), time.Date(2024, time.May, 31, 18, 0, 0, 0, time.UTC)},
+		{"at today's time", time.Date(2024, time.June, 1, 18, 0, 0, 0, time.UTC), time.Date(2024, time.June, 1, 18, 0, 0, 0, time.UTC)},
+		{"after today's time", time.Date(2024, time.June, 1, 23, 0, 0, 0, time.UTC), time.Date(2024, time.June, 1, 18, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lastSendAt("18:00", tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("lastSendAt(18:00, %s) = %s, want %s", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveDailyFreshRequiresClockTime(t *testing.T) {
+	t.Setenv("DIGEST_MODE", "daily-fresh")
+	t.Setenv("DIGEST_SEND_AT", "2024-06-01T18:00:00Z")
+	if _, err := (Config{}).resolve(); err == nil {
+		t.Fatal("expected an error for a DIGEST_SEND_AT that isn't daily")
+	}
+
+	t.Setenv("DIGEST_SEND_AT", "18:00")
+	rc, err := (Config{}).resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.DailySendAt != "18:00" || rc.Slack.SendAt != "" {
+		t.Errorf("DailySendAt = %q, SendAt = %q, want the digest posted by the run rather than scheduled", rc.DailySendAt, rc.Slack.SendAt)
+	}
+}
+
+func TestRunDigestModeDailyFresh(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "seen.json")
+	t.Setenv("STATE_FILE", state)
+	t.Setenv("DIGEST_MODE", "daily-fresh")
+	t.Setenv("DIGEST_SEND_AT", "18:00")
+	t.Setenv("DISPLAY_TIMEZONE", "UTC")
+	feed := newFeedServer(t, "authors.xml")
+	slack, payloads := newSlackServer(t, http.StatusOK)
+	rc := resolveConfig(t, Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL})
+
+	runAt := func(hour int) runState {
+		t.Helper()
+		if err := run(t.Context(), feed.Client(), rc, newRunReport(time.Date(2024, time.June, 1, hour, 0, 0, 0, time.UTC))); err != nil {
+			t.Fatalf("run at %d:00: %v", hour, err)
+		}
+		stored, err := loadState(state, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+
+	for _, hour := range []int{10, 12} {
+		if stored := runAt(hour); len(stored.Daily.Entries) != 3 || len(stored.Seen) != 0 {
+			t.Errorf("at %d:00 holding %d entries with %d feeds seen, want the 3 entries accumulated once", hour, len(stored.Daily.Entries), len(stored.Seen))
+		}
+	}
+	if len(*payloads) != 0 {
+		t.Fatalf("payloads = %+v, want nothing sent before DIGEST_SEND_AT", *payloads)
+	}
+
+	stored := runAt(18)
+	if len(*payloads) != 1 || !strings.Contains((*payloads)[0].Text, "3 new articles") {
+		t.Errorf("payloads = %+v, want one digest of the accumulated entries", *payloads)
+	}
+	if sent := time.Date(2024, time.June, 1, 18, 0, 0, 0, time.UTC); len(stored.Daily.Entries) != 0 || !stored.Daily.LastSent.Equal(sent) {
+		t.Errorf("daily = %+v, want it cleared and sent at %s", stored.Daily, sent)
+	}
+
+	runAt(20)
+	if len(*payloads) != 1 {
+		t.Errorf("payloads = %d, want nothing sent twice for one day", len(*payloads))
+	}
+}
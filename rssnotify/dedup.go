@@ -0,0 +1,137 @@
+package rssnotify
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// DEDUP_FIELD values naming the item field that identifies an entry for
+// de-duplication and the seen state. Any other value is a path to one of
+// the item's other elements (see parseDedupField).
+const (
+	dedupGUID  = "guid"  // The <guid> or Atom <id>, falling back to the link (the default)
+	dedupLink  = "link"  // The link, normalized under LINK_NORMALIZATION
+	dedupTitle = "title" // The title, for feeds that regenerate links and GUIDs
+)
+
+// parseDedupField validates a DEDUP_FIELD value, falling back to dedupGUID.
+// Paths are element names separated by "/", optionally ending in "@attr" to
+// select an attribute, e.g. "dc:identifier" or "source/@url". Namespace
+// prefixes are ignored, as elements are matched by local name.
+func parseDedupField(value string) string {
+	field := strings.TrimSpace(value)
+	switch lower := strings.ToLower(field); lower {
+	case "":
+		return dedupGUID
+	case dedupGUID, dedupLink, dedupTitle:
+		return lower
+	}
+	segments := strings.Split(field, "/")
+	for i, segment := range segments {
+		attr, isAttr := strings.CutPrefix(segment, "@")
+		if localName(attr) == "" || (isAttr && (i == 0 || i != len(segments)-1)) {
+			warnf("Warning: invalid DEDUP_FIELD %q, using %s\n", value, dedupGUID)
+			return dedupGUID
+		}
+	}
+	return field
+}
+
+// isElementPath reports whether field is a path rather than a named field.
+func isElementPath(field string) bool {
+	switch field {
+	case "", dedupGUID, dedupLink, dedupTitle:
+		return false
+	}
+	return true
+}
+
+// localName strips any namespace prefix from an element or attribute name.
+func localName(name string) string {
+	if _, local, ok := strings.Cut(name, ":"); ok {
+		return local
+	}
+	return name
+}
+
+// extraElement captures an item element without a dedicated Item field, so
+// DEDUP_FIELD can name it.
+type extraElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr     `xml:",any,attr"`
+	Value    string         `xml:",chardata"`
+	Children []extraElement `xml:",any"`
+}
+
+// lookupElement returns the trimmed value at path among elems, or "" when
+// no element along it has one.
+func lookupElement(elems []extraElement, path string) string {
+	name, rest, nested := strings.Cut(path, "/")
+	for _, elem := range elems {
+		if elem.XMLName.Local != localName(name) {
+			continue
+		}
+		if !nested {
+			if value := strings.TrimSpace(elem.Value); value != "" {
+				return value
+			}
+			continue
+		}
+		if attr, ok := strings.CutPrefix(rest, "@"); ok {
+			for _, a := range elem.Attrs {
+				if a.Name.Local == localName(attr) && strings.TrimSpace(a.Value) != "" {
+					return strings.TrimSpace(a.Value)
+				}
+			}
+			continue
+		}
+		if value := lookupElement(elem.Children, rest); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// dedupValue returns the entry's value for field, or "" when the entry
+// doesn't have one and key falls back to its link.
+func (entry FilteredEntry) dedupValue(field string) string {
+	switch field {
+	case dedupLink:
+		return ""
+	case dedupTitle:
+		return strings.TrimSpace(entry.Title)
+	case "", dedupGUID:
+		return strings.TrimSpace(entry.GUID)
+	default:
+		return strings.TrimSpace(entry.ID)
+	}
+}
+
+// warnEmptyDedupField warns when field is missing from most of a feed's
+// entries, as those are keyed by link instead and a typo in DEDUP_FIELD
+// would otherwise go unnoticed.
+func warnEmptyDedupField(entries []FilteredEntry, field, feedURL string) {
+	if field == dedupLink || field == dedupGUID || len(entries) == 0 {
+		return
+	}
+	empty := 0
+	for _, entry := range entries {
+		if entry.dedupValue(field) == "" {
+			empty++
+		}
+	}
+	switch {
+	case empty == len(entries):
+		warnf("Warning: DEDUP_FIELD %q not found in any entry of %s, using links\n", field, feedURL)
+	case empty*2 > len(entries):
+		warnf("Warning: DEDUP_FIELD %q is empty in %d of %d entries of %s, which use links instead\n", field, empty, len(entries), feedURL)
+	}
+}
+
+// dedupID returns the item's value at field when it is an element path.
+func (item Item) dedupID(field string) string {
+	if !isElementPath(field) {
+		return ""
+	}
+	return lookupElement(item.Extra, field)
+}
@@ -0,0 +1,85 @@
+package rssnotify
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseDedupField(t *testing.T) {
+	for value, want := range map[string]string{
+		"":               dedupGUID,
+		" Link ":         dedupLink,
+		"title":          dedupTitle,
+		"dc:identifier":  "dc:identifier",
+		"source/@url":    "source/@url",
+		"@url":           dedupGUID,
+		"source/@url/id": dedupGUID,
+		"source//id":     dedupGUID,
+		"dc:":            dedupGUID,
+	} {
+		if got := parseDedupField(value); got != want {
+			t.Errorf("parseDedupField(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestDedupeEntriesByField(t *testing.T) {
+	body, err := os.ReadFile("testdata/dedup.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]int{
+		dedupGUID:       3, // No GUIDs, and the links differ
+		dedupLink:       3,
+		dedupTitle:      2,
+		"dc:identifier": 2,
+		"source/@url":   2, // The merger has no source and keeps its link
+	}
+	for field, want := range tests {
+		entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories, DedupField: field})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := dedupeEntries(entries, field, normalizeBasic); len(got) != want {
+			t.Errorf("DEDUP_FIELD %q kept %d entries, want %d", field, len(got), want)
+		}
+	}
+}
+
+func TestElementPathKeysSeenState(t *testing.T) {
+	entry := FilteredEntry{Link: "https://domainincite.com/story?session=1", ID: "story-42", FeedURL: "https://domainincite.com/feed"}
+	if got := entry.seenKey(seenKeyGUID, "dc:identifier", normalizeBasic); got != "story-42" {
+		t.Errorf("seenKey = %q, want the element's value", got)
+	}
+	if got := (FilteredEntry{Link: "https://domainincite.com/merger"}).key("dc:identifier", normalizeBasic); got != "https://domainincite.com/merger" {
+		t.Errorf("key = %q, want the link when the element is missing", got)
+	}
+}
+
+func TestWarnEmptyDedupField(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Writer()
+	t.Cleanup(func() { log.SetOutput(out) })
+	log.SetOutput(&buf)
+
+	entries := []FilteredEntry{{ID: "story-42"}, {}, {}}
+	warnEmptyDedupField(entries, "dc:identifier", "https://domainincite.com/feed")
+	if !strings.Contains(buf.String(), "empty in 2 of 3 entries") {
+		t.Errorf("got %q, want a warning about the empty entries", buf.String())
+	}
+
+	buf.Reset()
+	warnEmptyDedupField(entries[1:], "dc:identifer", "https://domainincite.com/feed")
+	if !strings.Contains(buf.String(), "not found in any entry") {
+		t.Errorf("got %q, want a warning that the field is missing", buf.String())
+	}
+
+	buf.Reset()
+	warnEmptyDedupField(entries[:2], "dc:identifier", "https://domainincite.com/feed")
+	if buf.Len() != 0 {
+		t.Errorf("got %q, want no warning when half the entries have the field", buf.String())
+	}
+}
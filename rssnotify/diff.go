@@ -0,0 +1,93 @@
+package rssnotify
+
+import "strings"
+
+// DIGEST_MODE values selecting what a digest lists.
+const (
+	digestNew        = "new"         // Entries not notified before (the default)
+	digestDiff       = "diff"        // Entries added and removed since the last posted digest
+	digestDailyFresh = "daily-fresh" // Entries matched since the last daily digest, see dailyDigest
+)
+
+// parseDigestMode validates a DIGEST_MODE value, falling back to digestNew.
+func parseDigestMode(value string) string {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "":
+		return digestNew
+	case digestNew, digestDiff, digestDailyFresh:
+		return mode
+	default:
+		warnf("Warning: unknown DIGEST_MODE %q, using %s\n", value, digestNew)
+		return digestNew
+	}
+}
+
+// Change values of an entry in a DIGEST_MODE=diff digest.
+const (
+	changeNew  = "new"  // Matched now but not in the last posted digest
+	changeGone = "gone" // In the last posted digest but no longer matched
+)
+
+// diffDigest compares current, the entries matched by this run, with posted,
+// those of the last posted digest, identifying each by feed and key. It
+// returns the new entries in their current order followed by the gone ones
+// in their posted order, with Change set; nil means nothing changed.
+func diffDigest(current, posted []FilteredEntry, key func(FilteredEntry) string) []FilteredEntry {
+	was := make(seenEntries)
+	for _, entry := range posted {
+		was.add(entry.FeedURL, key(entry))
+	}
+	is := make(seenEntries)
+	var changes []FilteredEntry
+	for _, entry := range current {
+		is.add(entry.FeedURL, key(entry))
+		if !was.has(entry.FeedURL, key(entry)) {
+			entry.Change = changeNew
+			changes = append(changes, entry)
+		}
+	}
+	for _, entry := range posted {
+		if !is.has(entry.FeedURL, key(entry)) {
+			entry.Change = changeGone
+			changes = append(changes, entry)
+		}
+	}
+	return changes
+}
+
+// applyDiff returns posted updated with the delivered changes, the new
+// entries added and the gone ones removed. Changes withheld from the digest,
+// e.g. by MAX_ENTRIES, stay pending for the next run.
+func applyDiff(posted, changes []FilteredEntry, key func(FilteredEntry) string) []FilteredEntry {
+	gone := make(seenEntries)
+	for _, entry := range changes {
+		if entry.Change == changeGone {
+			gone.add(entry.FeedURL, key(entry))
+		}
+	}
+	var next []FilteredEntry
+	for _, entry := range posted {
+		if !gone.has(entry.FeedURL, key(entry)) {
+			next = append(next, entry)
+		}
+	}
+	for _, entry := range changes {
+		if entry.Change == changeNew {
+			entry.Change = ""
+			next = append(next, entry)
+		}
+	}
+	return next
+}
+
+// changeMarker returns the prefix marking entry as added or removed in a
+// DIGEST_MODE=diff digest, or "" outside one.
+func (entry FilteredEntry) changeMarker() string {
+	switch entry.Change {
+	case changeNew:
+		return "➕ "
+	case changeGone:
+		return "➖ "
+	}
+	return ""
+}
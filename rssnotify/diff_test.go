@@ -0,0 +1,104 @@
+package rssnotify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffDigest(t *testing.T) {
+	key := func(entry FilteredEntry) string { return entry.GUID }
+	entry := func(guid string) FilteredEntry { return FilteredEntry{GUID: guid, FeedURL: "https://example.com/feed"} }
+	guids := func(entries []FilteredEntry) []string {
+		var out []string
+		for _, entry := range entries {
+			out = append(out, entry.Change+":"+entry.GUID)
+		}
+		return out
+	}
+
+	posted := []FilteredEntry{entry("a"), entry("b")}
+	changes := diffDigest([]FilteredEntry{entry("c"), entry("a")}, posted, key)
+	if got, want := guids(changes), []string{"new:c", "gone:b"}; !slices.Equal(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+	if got := diffDigest(posted, posted, key); got != nil {
+		t.Errorf("changes = %v, want none for an unchanged set", guids(got))
+	}
+
+	// Only the delivered changes are applied, so a withheld one stays pending.
+	if got, want := guids(applyDiff(posted, changes[1:], key)), []string{":a"}; !slices.Equal(got, want) {
+		t.Errorf("posted = %v, want %v", got, want)
+	}
+	if got, want := guids(applyDiff(posted, changes, key)), []string{":a", ":c"}; !slices.Equal(got, want) {
+		t.Errorf("posted = %v, want %v", got, want)
+	}
+}
+
+func TestParseDigestMode(t *testing.T) {
+	for value, want := range map[string]string{"": digestNew, "new": digestNew, " Diff ": digestDiff, "weekly": digestNew} {
+		if got := parseDigestMode(value); got != want {
+			t.Errorf("parseDigestMode(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestRunDigestModeDiff(t *testing.T) {
+	t.Setenv("STATE_FILE", t.TempDir()+"/seen.json")
+	t.Setenv("DIGEST_MODE", "diff")
+	original, err := os.ReadFile("testdata/dateless.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(original)
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer feed.Close()
+	slack, payloads := newSlackServer(t, http.StatusOK)
+	cfg := Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL}
+	runOnce := func() {
+		t.Helper()
+		if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+
+	runOnce()
+	runOnce()
+	if len(*payloads) != 1 || !strings.Contains((*payloads)[0].Text, "3 new articles") {
+		t.Fatalf("payloads = %+v, want the first digest only, as nothing changed", *payloads)
+	}
+
+	// One item is replaced by another.
+	body = strings.Replace(body, "note-1", "note-4", 1)
+	body = regexp.MustCompile(`<title>Welcome</title>\s*<link>[^<]*</link>`).ReplaceAllString(body, "<title>Registry fees</title><link>https://notes.example.com/4</link>")
+	runOnce()
+	if len(*payloads) != 2 {
+		t.Fatalf("got %d payloads, want a digest of the change", len(*payloads))
+	}
+	var lines []string
+	for _, block := range (*payloads)[1].Blocks {
+		if block.Type == "section" {
+			lines = append(lines, block.Text.Text)
+		}
+	}
+	want := []string{
+		"*➕ new*", "➕ <https://notes.example.com/4|Registry fees>",
+		"*➖ gone*", "➖ <https://notes.example.com/1|Welcome>",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("sections = %q, want %q", lines, want)
+	}
+
+	runOnce()
+	if len(*payloads) != 2 {
+		t.Errorf("got %d payloads, want none once the change was posted", len(*payloads))
+	}
+}
@@ -0,0 +1,186 @@
+package rssnotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Discord rejects messages with more than 10 embeds and embed titles longer
+// than 256 characters.
+const (
+	discordMaxEmbeds     = 10
+	discordMaxTitleRunes = 256
+)
+
+// DiscordMessage structures a Discord webhook message
+// See: https://discord.com/developers/docs/resources/webhook#execute-webhook
+type DiscordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []DiscordEmbed `json:"embeds,omitempty"`
+}
+
+type DiscordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"` // ISO 8601, rendered in the reader's time zone
+	Footer      *DiscordEmbedFooter `json:"footer,omitempty"`
+}
+
+type DiscordEmbedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// DiscordNotifier delivers the digest to a Discord channel webhook as one
+// embed per entry.
+type DiscordNotifier struct {
+	WebhookURL string
+	Options    slackOptions
+	HTTP       notifierOptions // Overrides of the run's timeout and retries
+	Report     *runReport
+}
+
+// Send posts entries to the Discord webhook, or the heartbeat message when
+// there are none and NOTIFY_ON_EMPTY is enabled.
+func (n DiscordNotifier) Send(ctx context.Context, entries []FilteredEntry) error {
+	n.Options = n.Options.withDefaults()
+	ctx = n.HTTP.apply(ctx)
+	deliveryStart := time.Now()
+	err := sendNotificationToDiscord(ctx, n.WebhookURL, entries, n.Options)
+	n.Report.recordDelivery("discord", len(entries), time.Since(deliveryStart), err)
+	if err != nil {
+		return fmt.Errorf("error sending Discord notification: %w", err)
+	}
+	return nil
+}
+
+// buildDiscordMessages formats entries as embeds, splitting them across as
+// many messages as Discord's embed limit requires. Only the first message
+// carries the header as its content.
+func buildDiscordMessages(entries []FilteredEntry, opts slackOptions) []DiscordMessage {
+	var messages []DiscordMessage
+	for start := 0; start < len(entries); start += discordMaxEmbeds {
+		var msg DiscordMessage
+		if start == 0 {
+			msg.Content = "**" + opts.headerText() + "**"
+		}
+		for _, entry := range entries[start:min(start+discordMaxEmbeds, len(entries))] {
+			msg.Embeds = append(msg.Embeds, buildDiscordEmbed(entry, opts))
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// buildDiscordEmbed renders a single entry, showing its source in the footer.
+func buildDiscordEmbed(entry FilteredEntry, opts slackOptions) DiscordEmbed {
+	embed := DiscordEmbed{Title: truncateRunes(entry.changeMarker()+entry.Title, discordMaxTitleRunes), URL: entry.Link}
+	if opts.ShowDiscussLink && entry.DiscussLink != "" {
+		embed.Description = fmt.Sprintf("[discuss](%s)", entry.DiscussLink)
+	}
+	if opts.ShowEnclosureSize && entry.Enclosure != nil {
+		if size, ok := entry.Enclosure.size(); ok {
+			if embed.Description != "" {
+				embed.Description += " · "
+			}
+			embed.Description += formatBytes(size)
+		}
+	}
+	// Discord shows the timestamp in each reader's own time zone, which suits
+	// relative dates; a configured layout goes in the footer instead.
+	var published string
+	if !entry.Published.IsZero() {
+		if opts.relativeDates() {
+			embed.Timestamp = entry.Published.UTC().Format(time.RFC3339)
+		} else {
+			published = opts.formatPublished(entry.Published)
+		}
+	}
+	footer := entry.Source
+	if published != "" {
+		if footer != "" {
+			footer += " · "
+		}
+		footer += published
+	}
+	if footer != "" {
+		embed.Footer = &DiscordEmbedFooter{Text: footer}
+		if opts.ShowFavicon && entry.Source != "" {
+			embed.Footer.IconURL = entry.FaviconURL
+		}
+	}
+	return embed
+}
+
+// sendNotificationToDiscord sends the list of filtered entries to a Discord
+// webhook.
+func sendNotificationToDiscord(ctx context.Context, webhookURL string, entries []FilteredEntry, opts slackOptions) error {
+	post := postDiscordMessage
+	if opts.DryRun {
+		post = printPayload
+	} else if webhookURL == "" {
+		errorf("Error: DISCORD_WEBHOOK_URL is not set. Cannot send Discord notification.\n")
+		return fmt.Errorf("DISCORD_WEBHOOK_URL is not configured")
+	}
+
+	if len(entries) == 0 {
+		if !opts.NotifyOnEmpty {
+			infof("No new DNS-related entries found to send to Discord.\n")
+			return nil
+		}
+		infof("Sending empty digest heartbeat to Discord...\n")
+		msg := DiscordMessage{Content: "**" + opts.headerText() + "**\n" + opts.Messages.Empty}
+		if _, err := post(ctx, webhookURL, msg); err != nil {
+			return err
+		}
+		infof("Successfully sent heartbeat to Discord.\n")
+		return nil
+	}
+
+	messages := buildDiscordMessages(entries, opts)
+	logFields(fmt.Sprintf("Sending %d DNS entries to Discord in %d message(s)...", len(entries), len(messages)),
+		"notifier", "discord", "entry_count", len(entries), "message_count", len(messages))
+
+	var errs []error
+	for i, msg := range messages {
+		if _, err := post(ctx, webhookURL, msg); err != nil {
+			errs = append(errs, fmt.Errorf("message %d of %d: %w", i+1, len(messages), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	infof("Successfully sent notification to Discord.\n")
+	return nil
+}
+
+// postDiscordMessage POSTs a single JSON payload to the Discord webhook. The
+// returned body is empty on success, as Discord answers with 204 No Content.
+func postDiscordMessage(ctx context.Context, webhookURL string, payload any) (string, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling Discord payload to JSON: %w", err)
+	}
+	capture(ctx, "discord-payload", "json", payloadBytes)
+
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		return postWebhook(ctx, webhookURL, "application/json", payloadBytes)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error sending message to Discord: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("error from Discord API with status %d: %s", resp.StatusCode, string(responseBody))
+	}
+	return string(responseBody), nil
+}
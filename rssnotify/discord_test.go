@@ -0,0 +1,85 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newDiscordServer records the messages posted to a Discord webhook,
+// answering with status.
+func newDiscordServer(t *testing.T, status int) (*httptest.Server, *[]DiscordMessage) {
+	t.Helper()
+	var messages []DiscordMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg DiscordMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		messages = append(messages, msg)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &messages
+}
+
+func TestDiscordNotifierSend(t *testing.T) {
+	srv, messages := newDiscordServer(t, http.StatusNoContent)
+	entries := make([]FilteredEntry, discordMaxEmbeds+2)
+	for i := range entries {
+		entries[i] = FilteredEntry{Title: fmt.Sprintf("Entry %d", i), Link: fmt.Sprintf("https://domainincite.com/%d", i), Source: "Domain Incite"}
+	}
+	entries[0].Title = strings.Repeat("x", discordMaxTitleRunes+10)
+	entries[1].DiscussLink = "https://domainincite.com/1#comments"
+
+	report := newRunReport(time.Now())
+	n := DiscordNotifier{WebhookURL: srv.URL, Options: slackOptions{Messages: englishMessages, ShowDiscussLink: true}, Report: report}
+	if err := n.Send(t.Context(), entries); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(*messages) != 2 {
+		t.Fatalf("got %d messages, want the entries split at %d embeds", len(*messages), discordMaxEmbeds)
+	}
+	first, second := (*messages)[0], (*messages)[1]
+	if first.Content != "**📰 Daily DNS News Digest (Domain Incite)**" || second.Content != "" {
+		t.Errorf("contents = %q, %q, want the header on the first message only", first.Content, second.Content)
+	}
+	if len(first.Embeds) != discordMaxEmbeds || len(second.Embeds) != 2 {
+		t.Errorf("embeds = %d, %d", len(first.Embeds), len(second.Embeds))
+	}
+	if got := len([]rune(first.Embeds[0].Title)); got > discordMaxTitleRunes {
+		t.Errorf("title has %d runes, want at most %d", got, discordMaxTitleRunes)
+	}
+	if embed := first.Embeds[1]; embed.URL != entries[1].Link || embed.Description != "[discuss](https://domainincite.com/1#comments)" || embed.Footer.Text != "Domain Incite" {
+		t.Errorf("embed = %+v", embed)
+	}
+	if len(report.Deliveries) != 1 || report.Deliveries[0].Notifier != "discord" || !report.Deliveries[0].Success {
+		t.Errorf("deliveries = %+v, want a successful discord delivery", report.Deliveries)
+	}
+}
+
+func TestDiscordNotifierHeartbeatAndErrors(t *testing.T) {
+	srv, messages := newDiscordServer(t, http.StatusNoContent)
+	n := DiscordNotifier{WebhookURL: srv.URL, Options: slackOptions{Messages: englishMessages}}
+	if err := n.Send(t.Context(), nil); err != nil || len(*messages) != 0 {
+		t.Errorf("empty digest: err = %v, %d messages, want nothing sent", err, len(*messages))
+	}
+	n.Options.NotifyOnEmpty = true
+	if err := n.Send(t.Context(), nil); err != nil || len(*messages) != 1 || !strings.HasSuffix((*messages)[0].Content, englishMessages.Empty) {
+		t.Errorf("heartbeat: err = %v, messages = %+v", err, *messages)
+	}
+
+	if err := (DiscordNotifier{}).Send(t.Context(), []FilteredEntry{{Title: "A", Link: "https://example.com"}}); err == nil {
+		t.Error("expected an error without DISCORD_WEBHOOK_URL")
+	}
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 1})
+	rejected, _ := newDiscordServer(t, http.StatusBadRequest)
+	if err := (DiscordNotifier{WebhookURL: rejected.URL}).Send(ctx, []FilteredEntry{{Title: "A", Link: "https://example.com"}}); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("err = %v, want the 400 reported", err)
+	}
+}
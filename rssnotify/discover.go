@@ -0,0 +1,119 @@
+package rssnotify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxDiscoveryBodyBytes caps how much of an HTML page is read during feed
+// discovery. Feed <link> tags live in the <head> so this is plenty.
+const maxDiscoveryBodyBytes = 2 << 20
+
+var (
+	linkTagPattern = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	attrPattern    = regexp.MustCompile(`(?is)([a-z][a-z0-9_:-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// feedContentTypes are the <link type="..."> values that identify a feed.
+var feedContentTypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+}
+
+// discoverFeeds fetches the HTML page at pageURL and returns the absolute URLs
+// of any feeds it declares via <link rel="alternate" type="application/rss+xml">
+// (or the Atom equivalent), in document order. The page is fetched with the
+// feed client, so HTTP_TIMEOUT_SECONDS, RSS_MIN_TLS_VERSION and the retry
+// policy apply to it as well.
+func discoverFeeds(ctx context.Context, client *http.Client, pageURL string) ([]string, error) {
+	infof("Discovering feeds from: %s\n", pageURL)
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing page URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating page request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent(ctx))
+
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		return client.Do(req)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching page: received status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error reading page body: %w", err)
+	}
+
+	// Resolve against the final URL in case the page redirected.
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	return extractFeedLinks(string(body), base), nil
+}
+
+// extractFeedLinks returns the feed URLs declared by <link> tags in page,
+// resolved against base and with duplicates removed.
+func extractFeedLinks(page string, base *url.URL) []string {
+	var feeds []string
+	seen := make(map[string]bool)
+
+	for _, tag := range linkTagPattern.FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, m := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2] + m[3] + m[4])
+		}
+
+		if !hasToken(attrs["rel"], "alternate") {
+			continue
+		}
+		if !feedContentTypes[strings.ToLower(strings.TrimSpace(attrs["type"]))] {
+			continue
+		}
+		href := strings.TrimSpace(attrs["href"])
+		if href == "" {
+			continue
+		}
+
+		ref, err := url.Parse(href)
+		if err != nil {
+			warnf("Warning: skipping invalid feed link %q: %v\n", href, err)
+			continue
+		}
+		feed := base.ResolveReference(ref).String()
+		if !seen[feed] {
+			seen[feed] = true
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds
+}
+
+// hasToken reports whether the space-separated list contains token
+// (case-insensitively), as used by the HTML rel attribute.
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package rssnotify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestExtractFeedLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/")
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{
+			name: "none",
+			page: `<html><head><link rel="stylesheet" href="/style.css"><link rel="alternate" type="text/html" href="/en"></head></html>`,
+		},
+		{
+			name: "single relative",
+			page: `<link rel="alternate" type="application/rss+xml" href="feed.xml">`,
+			want: []string{"https://example.com/blog/feed.xml"},
+		},
+		{
+			name: "multiple in document order",
+			page: `<link rel="alternate" type="application/atom+xml" href="/atom.xml">
+				<LINK REL="Alternate Feed" TYPE="Application/RSS+XML" HREF='https://feeds.example.com/rss?a=1&amp;b=2'>
+				<link rel=alternate type=application/rss+xml href=/atom.xml>`,
+			want: []string{"https://example.com/atom.xml", "https://feeds.example.com/rss?a=1&b=2"},
+		},
+		{
+			name: "missing href",
+			page: `<link rel="alternate" type="application/rss+xml" href="  ">`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractFeedLinks(tt.page, base); !slices.Equal(got, tt.want) {
+				t.Errorf("extractFeedLinks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverFeedsRetries(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, `<link rel="alternate" type="application/rss+xml" href="/feed">`)
+	}))
+	defer srv.Close()
+	delay := retryBaseDelay
+	retryBaseDelay = 0
+	t.Cleanup(func() { retryBaseDelay = delay })
+
+	feeds, err := discoverFeeds(t.Context(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatalf("discoverFeeds: %v", err)
+	}
+	if want := []string{srv.URL + "/feed"}; requests != 2 || !slices.Equal(feeds, want) {
+		t.Errorf("got %v after %d requests, want %v after a retry", feeds, requests, want)
+	}
+}
@@ -0,0 +1,331 @@
+package rssnotify
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// stripHTML reduces an HTML fragment to plain text: tags are removed,
+// entities decoded and whitespace collapsed.
+func stripHTML(s string) string {
+	s = htmlTagPattern.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(s, " "))
+}
+
+// truncateRunes shortens s to at most n characters, ending in an ellipsis
+// when anything was cut.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+// summary returns the item's <description> (or Atom summary) as plain text.
+func (item Item) summary() string {
+	return stripHTML(item.Description)
+}
+
+// body returns the item's plain-text body, preferring <content:encoded> over
+// <description>.
+func (item Item) body() string {
+	if content := stripHTML(item.Content); content != "" {
+		return content
+	}
+	return stripHTML(item.Description)
+}
+
+// filterOptions controls which feed items are kept beyond the category match.
+type filterOptions struct {
+	Categories            []string        // Keep items with any of these categories
+	CategoryMatch         string          // How categories are compared: matchExact, matchCaseless or matchContains
+	MinCategoryMatches    int             // ...and how many distinct ones an item must carry; 0 or 1 keeps items with any
+	Keywords              []string        // ...or whose title contains any of these lowercased keywords
+	RequireEnclosureTypes []string        // Keep only items with an enclosure matching one of these (e.g. "audio/*")
+	ExcludeEnclosureTypes []string        // Drop items with an enclosure matching any of these
+	MaxEnclosureBytes     int64           // Drop items whose enclosure is larger than this; 0 disables
+	TitleExclude          *regexp.Regexp  // Drop items whose title matches, if set
+	MinContentLength      int             // Drop items whose plain-text body is shorter than this; 0 disables
+	AllowEmptyContent     bool            // Keep items with no body at all under MinContentLength
+	ForceInclude          map[string]bool // GUIDs or links kept regardless of the other filters
+	RejectDoctype         bool            // Refuse to parse feeds declaring a <!DOCTYPE>
+	MaxAge                time.Duration   // Drop items published longer ago than this; 0 disables
+	DropUndated           bool            // Also drop items without a parseable date under MaxAge
+	AllowDomains          []string        // Keep only links on these lowercased hosts or their subdomains, if set
+	BlockDomains          []string        // Drop links on these lowercased hosts or their subdomains
+	LinkNormalization     string          // How links are normalized for de-duplication, one of the normalize* constants
+	DedupField            string          // What identifies an entry for de-duplication, one of the dedup* constants or an element path
+
+	// Priority matchers don't filter items, they flag kept ones as urgent.
+	PriorityPattern    *regexp.Regexp // Titles matching this are high priority
+	PriorityCategories []string       // Lowercased categories that make an item high priority
+}
+
+// isPriority reports whether the item's title matches the priority pattern
+// or it carries one of the priority categories.
+func (f filterOptions) isPriority(item Item) bool {
+	if f.PriorityPattern != nil && f.PriorityPattern.MatchString(item.Title) {
+		return true
+	}
+	for _, cat := range item.Categories {
+		for _, value := range cat.values() {
+			for _, priority := range f.PriorityCategories {
+				if strings.ToLower(value) == priority {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isForceIncluded reports whether the item's GUID or link is on the force
+// include list, bypassing every other filter.
+func (f filterOptions) isForceIncluded(item Item, link string) bool {
+	if len(f.ForceInclude) == 0 {
+		return false
+	}
+	guid := strings.TrimSpace(item.GUID.Value)
+	return (guid != "" && f.ForceInclude[guid]) || (link != "" && f.ForceInclude[link])
+}
+
+// skipReason applies the filters an item must pass beyond the category match,
+// returning a description of the first one it fails, or "" if it passes.
+func (f filterOptions) skipReason(item Item, enclosure *Enclosure, published time.Time) string {
+	switch {
+	case !f.matchesAge(published):
+		return "maximum age"
+	case !f.matchesEnclosureFilters(item):
+		return "enclosure type filter"
+	case f.TitleExclude != nil && f.TitleExclude.MatchString(item.Title):
+		return "title exclude pattern"
+	case !f.matchesContentLength(item):
+		return "minimum content length"
+	case !f.matchesEnclosureSize(enclosure):
+		return "enclosure size limit"
+	}
+	return ""
+}
+
+// compilePattern compiles the regular expression set in the named variable,
+// returning nil when it's empty so the filter stays disabled.
+func compilePattern(name, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return re, nil
+}
+
+// defaultCategories preserves the original "dns" behaviour when
+// RSS_FILTER_CATEGORIES is unset.
+var defaultCategories = []string{"dns"}
+
+// parseCategories parses the RSS_FILTER_CATEGORIES list, falling back to
+// defaultCategories when it's unset or contains no usable values.
+func parseCategories(value string) []string {
+	if categories := splitList(value); len(categories) > 0 {
+		return categories
+	}
+	return defaultCategories
+}
+
+// splitList parses a comma-separated list, trimming whitespace and skipping
+// empty values.
+func splitList(value string) []string {
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// CATEGORY_MATCH_MODE values.
+const (
+	matchExact    = "exact"    // Whole category, same case
+	matchCaseless = "ci"       // Whole category, ignoring case
+	matchContains = "contains" // Substring of the category, ignoring case
+)
+
+// parseCategoryMatch validates a CATEGORY_MATCH_MODE value, falling back to
+// exact matching.
+func parseCategoryMatch(value string) string {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "":
+		return matchExact
+	case matchExact, matchCaseless, matchContains:
+		return mode
+	default:
+		warnf("Warning: unknown CATEGORY_MATCH_MODE %q, using %s\n", value, matchExact)
+		return matchExact
+	}
+}
+
+// matchCategory returns the configured category matched by the first of the
+// item's categories that matches one, and whether the item matched at least
+// MinCategoryMatches distinct configured categories. The configured name is
+// returned rather than the item's own, so CATEGORY_EMOJI and CATEGORY_COLORS
+// apply however loosely CATEGORY_MATCH_MODE matched.
+func (f filterOptions) matchCategory(item Item) (string, bool) {
+	first, matched := "", make(map[string]bool)
+	for _, cat := range item.Categories {
+		for _, value := range cat.values() {
+			for _, want := range f.Categories {
+				if want = strings.TrimSpace(want); !matched[want] && categoryMatches(f.CategoryMatch, value, want) {
+					if len(matched) == 0 {
+						first = want
+					}
+					matched[want] = true
+				}
+			}
+		}
+	}
+	if len(matched) == 0 || len(matched) < f.MinCategoryMatches {
+		return "", false
+	}
+	return first, true
+}
+
+// categoryMatches compares an item's trimmed category against a configured
+// one using mode.
+func categoryMatches(mode, value, want string) bool {
+	switch mode {
+	case matchCaseless:
+		return strings.EqualFold(value, want)
+	case matchContains:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(want))
+	default:
+		return value == want
+	}
+}
+
+// linkDomainReason checks the host of an absolute link against the domain
+// lists, returning why the link is dropped, or "" if it is kept. The
+// blocklist takes precedence over the allowlist.
+func (f filterOptions) linkDomainReason(link string) string {
+	if len(f.AllowDomains) == 0 && len(f.BlockDomains) == 0 {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Hostname() == "" {
+		return "unparseable link host"
+	}
+	host := strings.ToLower(u.Hostname())
+	if domain, ok := matchDomain(host, f.BlockDomains); ok {
+		return fmt.Sprintf("blocked domain %q", domain)
+	}
+	if len(f.AllowDomains) > 0 {
+		if _, ok := matchDomain(host, f.AllowDomains); !ok {
+			return fmt.Sprintf("domain %q not on the allowlist", host)
+		}
+	}
+	return ""
+}
+
+// matchDomain returns the first of domains that host is, or is a subdomain
+// of, and whether there was one.
+func matchDomain(host string, domains []string) (string, bool) {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// matchKeyword returns the first configured keyword found in the item's
+// title, ignoring case, and whether there was a match.
+func (f filterOptions) matchKeyword(item Item) (string, bool) {
+	title := strings.ToLower(item.Title)
+	for _, keyword := range f.Keywords {
+		if strings.Contains(title, keyword) {
+			return keyword, true
+		}
+	}
+	return "", false
+}
+
+// matchesEnclosureFilters reports whether item passes the enclosure type
+// filters. Items without an enclosure fail a require filter.
+func (f filterOptions) matchesEnclosureFilters(item Item) bool {
+	if len(f.RequireEnclosureTypes) > 0 && !item.hasEnclosureType(f.RequireEnclosureTypes) {
+		return false
+	}
+	if len(f.ExcludeEnclosureTypes) > 0 && item.hasEnclosureType(f.ExcludeEnclosureTypes) {
+		return false
+	}
+	return true
+}
+
+// hasEnclosureType reports whether any of the item's enclosures has a media
+// type matching one of patterns. Patterns support wildcards such as "audio/*".
+func (item Item) hasEnclosureType(patterns []string) bool {
+	for _, enc := range item.Enclosures {
+		mediaType := strings.ToLower(strings.TrimSpace(enc.Type))
+		if i := strings.Index(mediaType, ";"); i >= 0 {
+			mediaType = strings.TrimSpace(mediaType[:i]) // Drop parameters such as codecs
+		}
+		if mediaType == "" {
+			continue
+		}
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), mediaType); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesEnclosureSize reports whether enc is within the configured size
+// limit. Items with no enclosure, or a missing/invalid length, are kept.
+func (f filterOptions) matchesEnclosureSize(enc *Enclosure) bool {
+	if f.MaxEnclosureBytes <= 0 || enc == nil {
+		return true
+	}
+	n, ok := enc.size()
+	return !ok || n <= f.MaxEnclosureBytes
+}
+
+// matchesAge reports whether an item published at published is recent enough
+// under MaxAge. A zero published time means the item had no usable date.
+func (f filterOptions) matchesAge(published time.Time) bool {
+	if f.MaxAge <= 0 {
+		return true
+	}
+	if published.IsZero() {
+		return !f.DropUndated
+	}
+	return time.Since(published) <= f.MaxAge
+}
+
+// matchesContentLength reports whether the item's plain-text body meets the
+// configured minimum length in characters.
+func (f filterOptions) matchesContentLength(item Item) bool {
+	if f.MinContentLength <= 0 {
+		return true
+	}
+	body := item.body()
+	if body == "" {
+		return f.AllowEmptyContent
+	}
+	return utf8.RuneCountInString(body) >= f.MinContentLength
+}
@@ -0,0 +1,385 @@
+package rssnotify
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterRSSEntriesKeywords(t *testing.T) {
+	body := []byte(`<rss version="2.0"><channel><title>x</title>
+<item><title>Registry news</title><link>https://example.com/1</link><category>dns</category></item>
+<item><title>New DNSSEC rollout</title><link>https://example.com/2</link><category>security</category></item>
+<item><title>Quarterly results</title><link>https://example.com/3</link><category>business</category></item>
+</channel></rss>`)
+
+	tests := []struct {
+		name     string
+		keywords []string
+		want     []string
+	}{
+		{"categories only", nil, []string{"https://example.com/1"}},
+		{"keyword or category", []string{"dnssec"}, []string{"https://example.com/1", "https://example.com/2"}},
+		{"no keyword match", []string{"ipv6"}, []string{"https://example.com/1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories, Keywords: tt.keywords})
+			if err != nil {
+				t.Fatalf("filterRSSEntries: %v", err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d: %+v", len(entries), len(tt.want), entries)
+			}
+			for i, entry := range entries {
+				if entry.Link != tt.want[i] {
+					t.Errorf("entry %d = %s, want %s", i, entry.Link, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchCategoryModes(t *testing.T) {
+	item := Item{Categories: []Category{{Data: " DNS Security "}}}
+	tests := []struct {
+		mode string
+		want bool
+	}{
+		{matchExact, false},
+		{matchCaseless, false},
+		{matchContains, true},
+	}
+	for _, tt := range tests {
+		if _, got := (filterOptions{Categories: []string{"dns"}, CategoryMatch: tt.mode}).matchCategory(item); got != tt.want {
+			t.Errorf("mode %s matched = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+
+	item = Item{Categories: []Category{{Data: " DNS "}}}
+	if _, ok := (filterOptions{Categories: []string{"dns"}, CategoryMatch: matchCaseless}).matchCategory(item); !ok {
+		t.Error("ci mode didn't match a trimmed category differing in case")
+	}
+	if _, ok := (filterOptions{Categories: []string{"dns"}}).matchCategory(item); ok {
+		t.Error("exact mode matched a category differing in case")
+	}
+}
+
+func TestFilterRSSEntriesMinCategoryMatches(t *testing.T) {
+	body := []byte(`<rss version="2.0"><channel><title>x</title>
+<item><title>One match</title><link>https://example.com/1</link><category>dns</category><category>business</category></item>
+<item><title>Two matches</title><link>https://example.com/2</link><category>security</category><category>dns</category></item>
+<item><title>One category twice</title><link>https://example.com/3</link><category>dns</category><category domain="dns">dns</category></item>
+</channel></rss>`)
+	tests := []struct {
+		min  int
+		want []string
+	}{
+		{0, []string{"One match", "Two matches", "One category twice"}},
+		{1, []string{"One match", "Two matches", "One category twice"}},
+		{2, []string{"Two matches"}},
+		{3, nil},
+	}
+	for _, tt := range tests {
+		filters := filterOptions{Categories: []string{"dns", "security", "ipv6"}, MinCategoryMatches: tt.min}
+		entries, err := filterRSSEntries(body, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry.Title)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("min %d kept %q, want %q", tt.min, got, tt.want)
+		}
+		if tt.min == 2 && len(entries) == 1 && entries[0].Category != "security" {
+			t.Errorf("category = %q, want the first matched", entries[0].Category)
+		}
+	}
+}
+
+func TestFilterRSSEntriesContainsModeCategory(t *testing.T) {
+	body := []byte(`<rss version="2.0"><channel><title>x</title>
+<item><title>Resolver hardening</title><link>https://example.com/1</link><category>DNS Security</category></item>
+</channel></rss>`)
+
+	entries, err := filterRSSEntries(body, filterOptions{Categories: []string{" dns "}, CategoryMatch: matchContains})
+	if err != nil {
+		t.Fatalf("filterRSSEntries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if got := entries[0].Category; got != "dns" {
+		t.Errorf("category = %q, want the configured category", got)
+	}
+	opts := slackOptions{CategoryEmoji: map[string]string{"dns": "🌐"}}
+	if got := formatEntryLine(entries[0], opts); !strings.HasPrefix(got, "🌐 ") {
+		t.Errorf("entry line = %q, want the dns emoji bullet", got)
+	}
+}
+
+func TestFilterRSSEntriesCategoryFormats(t *testing.T) {
+	body := []byte(`<rss version="2.0"><channel><title>x</title>
+<item><title>CDATA</title><link>https://example.com/1</link><category><![CDATA[dns]]></category></item>
+<item><title>Plain text</title><link>https://example.com/2</link><category>dns</category></item>
+<item><title>Domain attribute</title><link>https://example.com/3</link><category domain="dns"/></item>
+<item><title>Term attribute</title><link>https://example.com/4</link><category term="dns"/></item>
+<item><title>Other</title><link>https://example.com/5</link><category domain="http://example.com/tags">business</category></item>
+</channel></rss>`)
+
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatalf("filterRSSEntries: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4: %+v", len(entries), entries)
+	}
+	for i, entry := range entries {
+		if entry.Category != "dns" {
+			t.Errorf("entry %d (%s) category = %q, want dns", i, entry.Title, entry.Category)
+		}
+	}
+}
+
+func TestLinkDomainReason(t *testing.T) {
+	f := filterOptions{AllowDomains: []string{"domainincite.com"}, BlockDomains: []string{"ads.domainincite.com"}}
+	tests := []struct {
+		link string
+		kept bool
+	}{
+		{"https://domainincite.com/1", true},
+		{"https://www.domainincite.com/1", true},
+		{"https://ads.domainincite.com/sponsored", false}, // Blocklist wins
+		{"https://notdomainincite.com/1", false},
+		{"https://example.com/1", false},
+		{"/relative", false},
+	}
+	for _, tt := range tests {
+		if reason := f.linkDomainReason(tt.link); (reason == "") != tt.kept {
+			t.Errorf("linkDomainReason(%q) = %q, want kept %v", tt.link, reason, tt.kept)
+		}
+	}
+
+	if reason := (filterOptions{}).linkDomainReason("/relative"); reason != "" {
+		t.Errorf("without lists, got %q, want every link kept", reason)
+	}
+	if reason := (filterOptions{BlockDomains: []string{"example.com"}}).linkDomainReason("https://domainincite.com/1"); reason != "" {
+		t.Errorf("with only a blocklist, got %q for an unlisted domain", reason)
+	}
+}
+
+func TestFilterRSSEntriesRejectDoctype(t *testing.T) {
+	body, err := os.ReadFile("testdata/doctype.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("default options: got %d entries, err %v; want the feed parsed", len(entries), err)
+	}
+	if _, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories, RejectDoctype: true}); !errors.Is(err, errDoctypeRejected) {
+		t.Errorf("RejectDoctype: err = %v, want errDoctypeRejected", err)
+	}
+}
+
+func TestFilterRSSEntriesUnescapesLinks(t *testing.T) {
+	body, err := os.ReadFile("testdata/entities.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://domainincite.com/?p=1&utm_source=rss",
+		"https://domainincite.com/registry’s-plan",
+		"https://domainincite.com/?a=1&copy=2",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Link != want[i] {
+			t.Errorf("entry %d link = %q, want %q", i, entry.Link, want[i])
+		}
+	}
+}
+
+func TestIsPriority(t *testing.T) {
+	filters := filterOptions{
+		PriorityPattern:    regexp.MustCompile(`(?i)outage|breach`),
+		PriorityCategories: []string{"security"},
+	}
+	tests := []struct {
+		name string
+		item Item
+		want bool
+	}{
+		{"title pattern", Item{Title: "Registry OUTAGE hits .example"}, true},
+		{"priority category", Item{Title: "Quiet news", Categories: []Category{{Data: " Security "}}}, true},
+		{"priority term attribute", Item{Title: "Quiet news", Categories: []Category{{Term: "security"}}}, true},
+		{"neither", Item{Title: "Quiet news", Categories: []Category{{Data: "dns"}}}, false},
+	}
+	for _, tt := range tests {
+		if got := filters.isPriority(tt.item); got != tt.want {
+			t.Errorf("%s: isPriority = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if (filterOptions{}).isPriority(Item{Title: "Registry outage"}) {
+		t.Error("flagged an item as priority with no priority filters configured")
+	}
+}
+
+func TestMatchesContentLength(t *testing.T) {
+	long := "<p>" + strings.Repeat("word ", 10) + "</p>"
+	tests := []struct {
+		name    string
+		filters filterOptions
+		item    Item
+		want    bool
+	}{
+		{"disabled", filterOptions{}, Item{Description: "hi"}, true},
+		{"short description", filterOptions{MinContentLength: 20}, Item{Description: "<b>too</b> short"}, false},
+		{"long description", filterOptions{MinContentLength: 20}, Item{Description: long}, true},
+		{"empty body dropped", filterOptions{MinContentLength: 20}, Item{}, false},
+		{"empty body allowed", filterOptions{MinContentLength: 20, AllowEmptyContent: true}, Item{Description: "<p></p>"}, true},
+		{"content preferred over description", filterOptions{MinContentLength: 20}, Item{Content: "<p>short</p>", Description: long}, false},
+		{"description used without content", filterOptions{MinContentLength: 20}, Item{Content: "<p></p>", Description: long}, true},
+		{"long content", filterOptions{MinContentLength: 20}, Item{Content: long, Description: "short"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filters.matchesContentLength(tt.item); got != tt.want {
+			t.Errorf("%s: matchesContentLength = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTitleExcludePattern(t *testing.T) {
+	re, err := compilePattern("RSS_TITLE_EXCLUDE_REGEX", `(?i)^sponsored:|\bwebinar\b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := filterOptions{TitleExclude: re}
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Sponsored: buy more domains", "title exclude pattern"},
+		{"Join our WEBINAR on DNSSEC", "title exclude pattern"},
+		{"Registry adopts DNSSEC", ""},
+		{"Webinars archive", ""},
+	}
+	for _, tt := range tests {
+		if got := filters.skipReason(Item{Title: tt.title}, nil, time.Time{}); got != tt.want {
+			t.Errorf("skipReason(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	if re, err := compilePattern("RSS_TITLE_EXCLUDE_REGEX", ""); re != nil || err != nil {
+		t.Errorf("empty pattern = %v, %v; want nil, nil", re, err)
+	}
+	_, err := compilePattern("RSS_TITLE_EXCLUDE_REGEX", "(unclosed")
+	if err == nil || !strings.Contains(err.Error(), "invalid RSS_TITLE_EXCLUDE_REGEX") {
+		t.Errorf("invalid pattern error = %v, want it to name the variable", err)
+	}
+}
+
+func TestFilterRSSEntriesEnclosureTypes(t *testing.T) {
+	body, err := os.ReadFile("testdata/enclosure-types.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name             string
+		require, exclude []string
+		want             []string
+	}{
+		{"unfiltered", nil, nil, []string{"audio", "video", "codecs", "article", "both"}},
+		{"require wildcard", []string{"audio/*"}, nil, []string{"audio", "codecs", "both"}},
+		{"require exact", []string{"video/mp4", "application/pdf"}, nil, []string{"video", "both"}},
+		{"exclude", nil, []string{"video/*"}, []string{"audio", "codecs", "article", "both"}},
+		{"require and exclude", []string{"audio/*"}, []string{"application/pdf"}, []string{"audio", "codecs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories, RequireEnclosureTypes: tt.require, ExcludeEnclosureTypes: tt.exclude})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, strings.TrimPrefix(entry.Link, "https://domainincite.com/"))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterRSSEntriesEnclosureSize(t *testing.T) {
+	body, err := os.ReadFile("testdata/enclosures.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries without a limit, want 4", len(entries))
+	}
+	sizes := []struct {
+		n  int64
+		ok bool
+	}{{4200000, true}, {0, false}, {0, false}, {250000000, true}}
+	for i, want := range sizes {
+		if n, ok := entries[i].Enclosure.size(); n != want.n || ok != want.ok {
+			t.Errorf("entry %d size = %d, %v; want %d, %v", i, n, ok, want.n, want.ok)
+		}
+	}
+
+	entries, err = filterRSSEntries(body, filterOptions{Categories: defaultCategories, MaxEnclosureBytes: 100000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links []string
+	for _, entry := range entries {
+		links = append(links, entry.Link)
+	}
+	want := "https://domainincite.com/ep1 https://domainincite.com/ep2 https://domainincite.com/ep3"
+	if got := strings.Join(links, " "); got != want {
+		t.Errorf("MaxEnclosureBytes kept %q, want %q", got, want)
+	}
+}
+
+func TestFilterRSSEntriesGUIDPermalink(t *testing.T) {
+	body, err := os.ReadFile("testdata/guid.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://domainincite.com/guid-default", "https://domainincite.com/guid-true"}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want only the %d with permalink GUIDs: %+v", len(entries), len(want), entries)
+	}
+	for i, entry := range entries {
+		if entry.Link != want[i] {
+			t.Errorf("entry %d link = %q, want %q", i, entry.Link, want[i])
+		}
+	}
+}
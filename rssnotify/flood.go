@@ -0,0 +1,154 @@
+package rssnotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// defaultPendingFile is where GUARD_AGAINST_FLOOD writes the entries of a
+// held run when PENDING_FILE is unset.
+const defaultPendingFile = "./pending.json"
+
+// Defaults of FLOOD_FACTOR and FLOOD_MIN_ENTRIES.
+const (
+	defaultFloodFactor     = 5
+	defaultFloodMinEntries = 10
+)
+
+// floodAverageRuns is roughly how many recent runs the running average of
+// matchHistory reflects.
+const floodAverageRuns = 10
+
+// floodGuard holds back a run whose entries sharply outnumber those of
+// recent runs, as when broadening RSS_FILTER_CATEGORIES matches a feed's
+// whole history, until they are released with -release-pending.
+type floodGuard struct {
+	Enabled     bool   // GUARD_AGAINST_FLOOD
+	Factor      int    // FLOOD_FACTOR, how many times the average a flood is
+	MinEntries  int    // FLOOD_MIN_ENTRIES, below which a run is never held
+	PendingFile string // PENDING_FILE, defaulting to defaultPendingFile
+}
+
+// matchHistory is the running average of how many entries recent runs sent.
+type matchHistory struct {
+	Runs    int     `json:"runs"`    // Runs recorded, held ones excluded
+	Average float64 `json:"average"` // Entries per run, weighted towards the last floodAverageRuns
+}
+
+// record adds a run that sent count entries to the average.
+func (h *matchHistory) record(count int) {
+	h.Runs++
+	h.Average += (float64(count) - h.Average) / float64(min(h.Runs, floodAverageRuns))
+}
+
+// floods reports whether a run sending count entries is a flood against
+// history. Without a recorded run there is nothing to compare with.
+func (g floodGuard) floods(count int, history matchHistory) bool {
+	return history.Runs > 0 && count >= g.MinEntries && float64(count) > float64(g.Factor)*history.Average
+}
+
+// pendingEntries is the content of the pending file.
+type pendingEntries struct {
+	HeldAt  time.Time       `json:"held_at"` // Start of the run that held them
+	Average float64         `json:"average"` // The running average they were compared with
+	Entries []FilteredEntry `json:"entries"`
+}
+
+// writePending writes the entries of a held run to the pending file at path,
+// replacing those of an earlier held run, which this run matched again.
+func writePending(path string, pending pendingEntries) error {
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling pending entries: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing pending file: %w", err)
+	}
+	return nil
+}
+
+// readPending reads the pending file at path.
+func readPending(path string) (pendingEntries, error) {
+	var pending pendingEntries
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pending, fmt.Errorf("no held entries to release: %s doesn't exist", path)
+	}
+	if err != nil {
+		return pending, fmt.Errorf("error reading pending file: %w", err)
+	}
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return pending, fmt.Errorf("error parsing pending file: %w", err)
+	}
+	return pending, nil
+}
+
+// releasePending sends the entries a GUARD_AGAINST_FLOOD run held through
+// every notifier, then marks them seen and removes the pending file. Like a
+// run, nothing is recorded unless every notifier succeeds, so a failed
+// release can be repeated.
+func releasePending(ctx context.Context, rc runConfig, report *runReport) error {
+	path := rc.Flood.PendingFile
+	pending, err := readPending(path)
+	if err != nil {
+		return err
+	}
+	infof("Releasing %d entries held at %s.\n", len(pending.Entries), pending.HeldAt.Format(time.RFC3339))
+	report.NewEntries = len(pending.Entries)
+
+	state, err := loadState(rc.StateFile, rc.FeedURLs)
+	if err != nil {
+		return err
+	}
+	opts := rc.Slack
+	opts.Canvases = state.Canvases
+	notifiers, err := newNotifiers(rc.Config, opts, report)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, notifier := range notifiers {
+		if err := notifier.Send(ctx, pending.Entries); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if rc.Slack.DryRun {
+		infof("Dry run: leaving %s and %s unchanged.\n", rc.StateFile, path)
+		return nil
+	}
+	count(ctx, runStats{Sent: len(pending.Entries)})
+
+	var sinks []string
+	if rc.DedupScope == dedupScopeSink {
+		for _, notifier := range notifiers {
+			sinks = append(sinks, sinkID(notifier))
+		}
+		state.adoptSinks(sinks)
+	}
+	for _, entry := range pending.Entries {
+		key := entry.seenKey(rc.SeenKey, rc.Filters.DedupField, rc.Filters.LinkNormalization)
+		state.Seen.add(entry.FeedURL, key)
+		for _, sink := range sinks {
+			state.Sinks[sink].add(entry.FeedURL, key)
+		}
+		if rc.DedupWindow > 0 {
+			state.recordPublished(entry.FeedURL, key, entry.Published)
+		}
+	}
+	if err := saveState(rc.StateFile, state); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("error removing pending file: %w", err)
+	}
+	infof("Released %d held entries.\n", len(pending.Entries))
+	return nil
+}
@@ -0,0 +1,108 @@
+package rssnotify
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFloodGuardFloods(t *testing.T) {
+	guard := floodGuard{Enabled: true, Factor: 5, MinEntries: 10}
+	tests := []struct {
+		name    string
+		count   int
+		history matchHistory
+		want    bool
+	}{
+		{"no history", 100, matchHistory{}, false},
+		{"within the factor", 12, matchHistory{Runs: 5, Average: 3}, false},
+		{"over the factor", 16, matchHistory{Runs: 5, Average: 3}, true},
+		{"under the minimum", 9, matchHistory{Runs: 5, Average: 0.5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := guard.floods(tt.count, tt.history); got != tt.want {
+				t.Errorf("floods(%d, %+v) = %v, want %v", tt.count, tt.history, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchHistoryRecord(t *testing.T) {
+	var history matchHistory
+	for _, count := range []int{4, 2} {
+		history.record(count)
+	}
+	if history.Runs != 2 || history.Average != 3 {
+		t.Errorf("history = %+v, want the mean of the first runs", history)
+	}
+
+	for range 100 {
+		history.record(0)
+	}
+	if history.Average > 0.01 {
+		t.Errorf("average = %v, want it to follow the recent runs", history.Average)
+	}
+}
+
+func TestRunFloodGuardAndRelease(t *testing.T) {
+	dir := t.TempDir()
+	state, pending := filepath.Join(dir, "seen.json"), filepath.Join(dir, "pending.json")
+	t.Setenv("STATE_FILE", state)
+	t.Setenv("PENDING_FILE", pending)
+	t.Setenv("GUARD_AGAINST_FLOOD", "true")
+	t.Setenv("FLOOD_MIN_ENTRIES", "2")
+	if err := os.WriteFile(state, []byte(`{"feeds": {}, "matched": {"runs": 4, "average": 0.25}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	feed := newFeedServer(t, "authors.xml")
+	slack, payloads := newSlackServer(t, http.StatusOK)
+	cfg := Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL}
+
+	report := newRunReport(time.Now())
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), report); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(*payloads) != 0 {
+		t.Fatalf("payloads = %+v, want delivery held", *payloads)
+	}
+	if report.Held != 3 {
+		t.Errorf("report held %d entries, want 3", report.Held)
+	}
+	held, err := readPending(pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(held.Entries) != 3 || held.Average != 0.25 {
+		t.Errorf("pending = %+v, want the 3 entries and the average", held)
+	}
+
+	if err := releasePending(t.Context(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+		t.Fatalf("releasePending: %v", err)
+	}
+	if len(*payloads) != 1 {
+		t.Errorf("payloads = %d, want the held entries sent once", len(*payloads))
+	}
+	if _, err := os.Stat(pending); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("pending file still exists: %v", err)
+	}
+
+	// The released entries are seen, so the next run is quiet but recorded.
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if len(*payloads) != 1 {
+		t.Errorf("payloads = %d, want nothing sent again", len(*payloads))
+	}
+	stored, err := loadState(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Seen[feed.URL]) != 3 || stored.Matched.Runs != 5 {
+		t.Errorf("state = %+v, want the released entries seen and the quiet run recorded", stored)
+	}
+}
@@ -0,0 +1,229 @@
+package rssnotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"time"
+)
+
+// googleChatMaxMessageBytes keeps each Google Chat message safely below the
+// API's 32,000 byte message size limit.
+const googleChatMaxMessageBytes = 28000
+
+// GoogleChatMessage structures a Google Chat card message
+// See: https://developers.google.com/workspace/chat/api/reference/rest/v1/cards
+type GoogleChatMessage struct {
+	Text    string           `json:"text,omitempty"` // Notification fallback text
+	CardsV2 []GoogleChatCard `json:"cardsV2"`
+}
+
+type GoogleChatCard struct {
+	CardID string             `json:"cardId"`
+	Card   GoogleChatCardBody `json:"card"`
+}
+
+type GoogleChatCardBody struct {
+	Header   *GoogleChatCardHeader `json:"header,omitempty"`
+	Sections []GoogleChatSection   `json:"sections"`
+}
+
+type GoogleChatCardHeader struct {
+	Title string `json:"title"`
+}
+
+type GoogleChatSection struct {
+	Widgets []GoogleChatWidget `json:"widgets"`
+}
+
+type GoogleChatWidget struct {
+	DecoratedText *GoogleChatDecoratedText `json:"decoratedText,omitempty"`
+}
+
+type GoogleChatDecoratedText struct {
+	StartIcon   *GoogleChatIcon    `json:"startIcon,omitempty"`
+	Text        string             `json:"text"`
+	BottomLabel string             `json:"bottomLabel,omitempty"` // Shown under the text, e.g. the publication date
+	WrapText    bool               `json:"wrapText"`
+	OnClick     *GoogleChatOnClick `json:"onClick,omitempty"`
+}
+
+type GoogleChatIcon struct {
+	IconURL string `json:"iconUrl"`
+}
+
+type GoogleChatOnClick struct {
+	OpenLink GoogleChatOpenLink `json:"openLink"`
+}
+
+type GoogleChatOpenLink struct {
+	URL string `json:"url"`
+}
+
+// GoogleChatNotifier delivers the digest to a Google Chat space webhook as
+// cardsV2 messages.
+type GoogleChatNotifier struct {
+	WebhookURL string
+	Options    slackOptions
+	HTTP       notifierOptions // Overrides of the run's timeout and retries
+	Report     *runReport
+}
+
+// Send posts entries to the Google Chat webhook. An empty digest sends
+// nothing unless NotifyOnEmpty asks for a heartbeat.
+func (n GoogleChatNotifier) Send(ctx context.Context, entries []FilteredEntry) error {
+	n.Options = n.Options.withDefaults()
+	ctx = n.HTTP.apply(ctx)
+	deliveryStart := time.Now()
+	err := sendNotificationToGoogleChat(ctx, n.WebhookURL, entries, n.Options)
+	n.Report.recordDelivery("google-chat", len(entries), time.Since(deliveryStart), err)
+	if err != nil {
+		return fmt.Errorf("error sending Google Chat notification: %w", err)
+	}
+	return nil
+}
+
+// buildGoogleChatMessages formats entries as cardsV2 messages with a
+// decoratedText widget per entry, splitting them across as many messages as
+// needed to respect Google Chat's message size limit. Only the first message
+// carries the header and fallback text.
+func buildGoogleChatMessages(entries []FilteredEntry, opts slackOptions) []GoogleChatMessage {
+	headerText := opts.headerText()
+	first := fmt.Sprintf("<%s|%s>", entries[0].Link, entries[0].Title)
+
+	newMessage := func(index int) GoogleChatMessage {
+		msg := GoogleChatMessage{
+			CardsV2: []GoogleChatCard{{
+				CardID: fmt.Sprintf("digest-%d", index),
+				Card:   GoogleChatCardBody{Sections: []GoogleChatSection{{}}},
+			}},
+		}
+		if index == 0 {
+			msg.Text = opts.Messages.render(opts.Messages.Fallback, len(entries), first)
+			msg.CardsV2[0].Card.Header = &GoogleChatCardHeader{Title: headerText}
+		}
+		return msg
+	}
+
+	var messages []GoogleChatMessage
+	msg := newMessage(0)
+	size := estimateJSONSize(msg)
+
+	for _, entry := range entries {
+		widget := GoogleChatWidget{DecoratedText: &GoogleChatDecoratedText{
+			Text:     entry.changeMarker() + html.EscapeString(entry.Title), // decoratedText supports HTML formatting
+			WrapText: true,
+			OnClick:  &GoogleChatOnClick{OpenLink: GoogleChatOpenLink{URL: entry.Link}},
+		}}
+		if !entry.Published.IsZero() {
+			widget.DecoratedText.BottomLabel = opts.formatPublished(entry.Published)
+		}
+		if opts.ShowFavicon && entry.FaviconURL != "" {
+			widget.DecoratedText.StartIcon = &GoogleChatIcon{IconURL: entry.FaviconURL}
+		}
+		widgetSize := estimateJSONSize(widget) + 1 // Separating comma
+
+		section := &msg.CardsV2[0].Card.Sections[0]
+		if len(section.Widgets) > 0 && size+widgetSize > googleChatMaxMessageBytes {
+			messages = append(messages, msg)
+			msg = newMessage(len(messages))
+			size = estimateJSONSize(msg)
+			section = &msg.CardsV2[0].Card.Sections[0]
+		}
+		section.Widgets = append(section.Widgets, widget)
+		size += widgetSize
+	}
+	return append(messages, msg)
+}
+
+// estimateJSONSize returns the marshalled size of v in bytes.
+func estimateJSONSize(v any) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// sendNotificationToGoogleChat sends the list of filtered entries to a Google
+// Chat space webhook.
+func sendNotificationToGoogleChat(ctx context.Context, webhookURL string, entries []FilteredEntry, opts slackOptions) error {
+	post := postGoogleChatMessage
+	if opts.DryRun {
+		post = printPayload
+	}
+
+	if len(entries) == 0 {
+		if !opts.NotifyOnEmpty {
+			infof("No new DNS-related entries found to send to Google Chat.\n")
+			return nil
+		}
+		infof("Sending empty digest heartbeat to Google Chat...\n")
+		msg := GoogleChatMessage{
+			Text: opts.Messages.Empty,
+			CardsV2: []GoogleChatCard{{
+				CardID: "digest-0",
+				Card: GoogleChatCardBody{
+					Header: &GoogleChatCardHeader{Title: opts.headerText()},
+					Sections: []GoogleChatSection{{Widgets: []GoogleChatWidget{{
+						DecoratedText: &GoogleChatDecoratedText{Text: html.EscapeString(opts.Messages.Empty), WrapText: true},
+					}}}},
+				},
+			}},
+		}
+		if _, err := post(ctx, webhookURL, msg); err != nil {
+			return err
+		}
+		infof("Successfully sent heartbeat to Google Chat.\n")
+		return nil
+	}
+
+	messages := buildGoogleChatMessages(entries, opts)
+	logFields(fmt.Sprintf("Sending %d DNS entries to Google Chat in %d message(s)...", len(entries), len(messages)),
+		"notifier", "google-chat", "entry_count", len(entries), "message_count", len(messages))
+
+	var errs []error
+	for i, msg := range messages {
+		if _, err := post(ctx, webhookURL, msg); err != nil {
+			errs = append(errs, fmt.Errorf("message %d of %d: %w", i+1, len(messages), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		infof("Dry run: would have sent %d entries in %d message(s) to Google Chat.\n", len(entries), len(messages))
+		return nil
+	}
+	infof("Successfully sent notification to Google Chat.\n")
+	return nil
+}
+
+// postGoogleChatMessage POSTs a single JSON payload to the Google Chat
+// webhook, returning the response body on success.
+func postGoogleChatMessage(ctx context.Context, webhookURL string, payload any) (string, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling Google Chat payload to JSON: %w", err)
+	}
+	capture(ctx, "google-chat-payload", "json", payloadBytes)
+
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		return postWebhook(ctx, webhookURL, "application/json; charset=UTF-8", payloadBytes)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error sending message to Google Chat: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("error from Google Chat API with status %d: %s", resp.StatusCode, string(responseBody))
+	}
+	return string(responseBody), nil
+}
@@ -0,0 +1,70 @@
+package rssnotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendNotificationToGoogleChatDryRun(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+	var out bytes.Buffer
+	payloadOut = &out
+	t.Cleanup(func() { payloadOut = os.Stdout })
+
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+	opts := slackOptions{Messages: englishMessages, DryRun: true}
+	if err := sendNotificationToGoogleChat(t.Context(), srv.URL, entries, opts); err != nil {
+		t.Fatalf("sendNotificationToGoogleChat: %v", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("server received %d requests during a dry run, want 0", requests)
+	}
+	if !strings.Contains(out.String(), `"cardsV2"`) {
+		t.Errorf("printed payload = %s, want the cardsV2 message", out.String())
+	}
+}
+
+func TestGoogleChatNotifierSend(t *testing.T) {
+	var got GoogleChatMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json; charset=UTF-8" {
+			t.Errorf("Content-Type = %q, want application/json; charset=UTF-8", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	report := newRunReport(time.Now())
+	entries := []FilteredEntry{{Title: "Registry <raises> prices", Link: "https://domainincite.com/1"}}
+	notifier := GoogleChatNotifier{WebhookURL: srv.URL, Options: slackOptions{Messages: englishMessages}, Report: report}
+	if err := notifier.Send(t.Context(), entries); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(got.CardsV2) != 1 || got.CardsV2[0].Card.Header == nil {
+		t.Fatalf("payload = %+v, want one card with a header", got)
+	}
+	widgets := got.CardsV2[0].Card.Sections[0].Widgets
+	if len(widgets) != 1 || widgets[0].DecoratedText == nil {
+		t.Fatalf("widgets = %+v, want one decoratedText widget", widgets)
+	}
+	if text := widgets[0].DecoratedText; text.Text != "Registry &lt;raises&gt; prices" || text.OnClick.OpenLink.URL != entries[0].Link {
+		t.Errorf("decoratedText = %+v, want the escaped title linking to the entry", text)
+	}
+	if len(report.Deliveries) != 1 || report.Deliveries[0].Notifier != "google-chat" || !report.Deliveries[0].Success {
+		t.Errorf("deliveries = %+v, want a successful google-chat delivery", report.Deliveries)
+	}
+}
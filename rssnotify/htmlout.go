@@ -0,0 +1,113 @@
+package rssnotify
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+)
+
+// OUTPUT values.
+const (
+	outputNotify = "notify" // Deliver the digest via the notifiers (the default)
+	outputHTML   = "html"   // Write the digest to an HTML file instead
+)
+
+// defaultOutputFile is where OUTPUT=html writes when OUTPUT_FILE is unset.
+const defaultOutputFile = "./digest.html"
+
+// parseOutput validates an OUTPUT value, falling back to outputNotify.
+func parseOutput(value string) string {
+	switch output := strings.ToLower(strings.TrimSpace(value)); output {
+	case "":
+		return outputNotify
+	case outputNotify, outputHTML:
+		return output
+	default:
+		warnf("Warning: unknown OUTPUT %q, using %s\n", value, outputNotify)
+		return outputNotify
+	}
+}
+
+// htmlDigestTemplate renders a self-contained page listing the entries.
+// html/template escapes the feed-supplied titles and links.
+var htmlDigestTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+li { margin-bottom: 0.75rem; }
+.meta { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Generated {{.Generated}}</p>
+{{- if .Entries}}
+<ul>
+{{- range .Entries}}
+<li><a href="{{.Link}}">{{.Title}}</a>
+<div class="meta">{{if .Date}}{{.Date}} · {{end}}{{.Source}}</div>
+{{- if .Description}}
+<div>{{.Description}}</div>
+{{- end}}
+</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>{{.Empty}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlEntry is an entry as shown on the HTML page.
+type htmlEntry struct {
+	Title, Link, Date, Source, Description string
+}
+
+// writeHTMLDigest renders entries as an HTML page at path, using the same
+// header text and description length as the Slack digest.
+func writeHTMLDigest(path string, entries []FilteredEntry, opts slackOptions, now time.Time) error {
+	location := opts.Location
+	if location == nil {
+		location = time.Local
+	}
+	opts.Location = location // For formatPublished
+	page := struct {
+		Title, Generated, Empty string
+		Entries                 []htmlEntry
+	}{
+		Title:     opts.headerText(),
+		Generated: now.In(location).Format("Jan 2, 2006 15:04 MST"),
+		Empty:     opts.Messages.Empty,
+	}
+	for _, entry := range entries {
+		e := htmlEntry{Title: entry.Title, Link: entry.Link, Source: entry.Source}
+		if !entry.Published.IsZero() {
+			if opts.relativeDates() {
+				// The page outlives its generation, so show the full date.
+				e.Date = entry.Published.In(location).Format("Jan 2, 2006")
+			} else {
+				e.Date = opts.formatPublished(entry.Published)
+			}
+		}
+		if opts.DescriptionLength > 0 {
+			e.Description = truncateRunes(entry.Description, opts.DescriptionLength)
+		}
+		page.Entries = append(page.Entries, e)
+	}
+
+	var buf bytes.Buffer
+	if err := htmlDigestTemplate.Execute(&buf, page); err != nil {
+		return fmt.Errorf("error rendering HTML digest: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("error writing HTML digest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package rssnotify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTMLDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "digest.html")
+	entries := []FilteredEntry{
+		{
+			Title:     "Registry <b>raises</b> prices",
+			Link:      "https://domainincite.com/1?a=1&b=2",
+			Source:    "Domain Incite",
+			Published: time.Date(2025, 5, 15, 10, 0, 0, 0, time.UTC),
+		},
+		{Title: "Sneaky", Link: "javascript:alert(1)"},
+	}
+	opts := slackOptions{Messages: englishMessages, Location: time.UTC}
+
+	if err := writeHTMLDigest(path, entries, opts, time.Now()); err != nil {
+		t.Fatalf("writeHTMLDigest: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"<title>📰 Daily DNS News Digest (Domain Incite)</title>",
+		`<a href="https://domainincite.com/1?a=1&amp;b=2">Registry &lt;b&gt;raises&lt;/b&gt; prices</a>`,
+		"May 15, 2025 · Domain Incite",
+		`<a href="#ZgotmplZ">Sneaky</a>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page missing %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestWriteHTMLDigestEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "digest.html")
+	if err := writeHTMLDigest(path, nil, slackOptions{Messages: englishMessages}, time.Now()); err != nil {
+		t.Fatalf("writeHTMLDigest: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), englishMessages.Empty) {
+		t.Errorf("page = %s, want the empty digest message", data)
+	}
+}
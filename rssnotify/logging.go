@@ -0,0 +1,140 @@
+package rssnotify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// levelFatal marks the last line logged before exiting on an error.
+const levelFatal = slog.Level(12)
+
+// jsonLogs is set when LOG_FORMAT=json routes logging through slog.
+var jsonLogs bool
+
+// logLevel is the least severe level logged, set from LOG_LEVEL.
+var logLevel = slog.LevelInfo
+
+// parseLogLevel validates a LOG_LEVEL value, falling back to info.
+func parseLogLevel(value string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug
+	case "", "info":
+		return slog.LevelInfo
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		log.Printf("Warning: unknown LOG_LEVEL %q, using info\n", value)
+		return slog.LevelInfo
+	}
+}
+
+// setupLogging applies LOG_FORMAT and the minimum level. The default "text"
+// keeps the standard log output; "json" writes one JSON object per line to
+// stderr, and the log package's functions are routed through the same
+// handler so any remaining log.Printf calls are captured too.
+func setupLogging(format string, level slog.Level) error {
+	logLevel = level
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return nil
+	case "json":
+		handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevel})
+		slog.SetDefault(slog.New(prefixLevelHandler{handler}))
+		jsonLogs = true
+		return nil
+	default:
+		return fmt.Errorf("unknown LOG_FORMAT %q, expected text or json", format)
+	}
+}
+
+// replaceLevel names levelFatal, which slog would otherwise print as
+// "ERROR+4".
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == levelFatal {
+			a.Value = slog.StringValue("FATAL")
+		}
+	}
+	return a
+}
+
+// prefixLevelHandler raises the level of records logged through the log
+// package, which all arrive as INFO, based on the "Warning:" and "Error"
+// prefixes the messages already use.
+type prefixLevelHandler struct {
+	slog.Handler
+}
+
+func (h prefixLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level == slog.LevelInfo {
+		switch msg := r.Message; {
+		case strings.HasPrefix(msg, "Warning"):
+			r.Level = slog.LevelWarn
+		case strings.HasPrefix(msg, "Error"), strings.HasPrefix(msg, "Critical Error"):
+			r.Level = slog.LevelError
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h prefixLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return prefixLevelHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h prefixLevelHandler) WithGroup(name string) slog.Handler {
+	return prefixLevelHandler{h.Handler.WithGroup(name)}
+}
+
+// logf logs a formatted message at level, unless LOG_LEVEL filters it out.
+func logf(level slog.Level, format string, args ...any) {
+	if level < logLevel {
+		return
+	}
+	if !jsonLogs {
+		log.Printf(format, args...)
+		return
+	}
+	slog.Log(context.Background(), level, strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+}
+
+// debugf logs per-entry detail, such as each matched entry.
+func debugf(format string, args ...any) { logf(slog.LevelDebug, format, args...) }
+
+// infof logs the progress of a run.
+func infof(format string, args ...any) { logf(slog.LevelInfo, format, args...) }
+
+// warnf logs a problem the run recovers from.
+func warnf(format string, args ...any) { logf(slog.LevelWarn, format, args...) }
+
+// errorf logs a failure.
+func errorf(format string, args ...any) { logf(slog.LevelError, format, args...) }
+
+// logFields logs msg at info with structured fields given as alternating keys
+// and values (e.g. "feed_url", url). Text logs print msg alone, as before.
+func logFields(msg string, fields ...any) {
+	if slog.LevelInfo < logLevel {
+		return
+	}
+	if !jsonLogs {
+		log.Println(msg)
+		return
+	}
+	slog.Info(msg, fields...)
+}
+
+// fatalf logs a formatted message and exits with status 1, as a FATAL
+// object when logging JSON.
+func fatalf(format string, args ...any) {
+	if !jsonLogs {
+		log.Fatalf(format, args...)
+	}
+	slog.Log(context.Background(), levelFatal, strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+	os.Exit(1)
+}
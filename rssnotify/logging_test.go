@@ -0,0 +1,80 @@
+package rssnotify
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"DEBUG":   slog.LevelDebug,
+		" warn ":  slog.LevelWarn,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+		"loud":    slog.LevelInfo,
+	}
+	for value, want := range tests {
+		if got := parseLogLevel(value); got != want {
+			t.Errorf("parseLogLevel(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestLogfFiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	out, level := log.Writer(), logLevel
+	t.Cleanup(func() {
+		log.SetOutput(out)
+		logLevel = level
+	})
+	log.SetOutput(&buf)
+
+	logLevel = slog.LevelWarn
+	debugf("Found DNS entry by category: '%s'\n", "Registry raises prices")
+	infof("Starting Go script\n")
+	warnf("Warning: skipped %d malformed item(s) in feed\n", 1)
+	errorf("Error: SLACK_WEBHOOK_URL is not set.\n")
+
+	got := buf.String()
+	for _, want := range []string{"Warning: skipped", "Error: SLACK_WEBHOOK_URL"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Found DNS entry", "Starting Go script"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("output contains %q below the warn level:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestRunBenchmarkSilencesJSONLogs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, level, json := slog.Default(), logLevel, jsonLogs
+	t.Cleanup(func() {
+		slog.SetDefault(logger)
+		logLevel, jsonLogs = level, json
+	})
+	handler := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	slog.SetDefault(handler)
+	logLevel, jsonLogs = slog.LevelDebug, true
+
+	body, err := os.ReadFile("testdata/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runBenchmark(body, 2, filterOptions{Categories: defaultCategories}); err != nil {
+		t.Fatalf("runBenchmark: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("benchmark logged through slog:\n%s", buf.String())
+	}
+	if slog.Default() != handler {
+		t.Error("slog default handler wasn't restored")
+	}
+}
@@ -0,0 +1,67 @@
+package rssnotify
+
+import "strings"
+
+// Media holds the Media RSS elements of an item or Atom entry. It is
+// embedded so both formats decode it the same way; media:group nests the
+// same elements, as e.g. YouTube feeds do.
+// See: https://www.rssboard.org/media-rss
+//
+// Title, Description and Categories are never used, but must be declared:
+// the un-namespaced tags of Item and AtomEntry would otherwise also capture
+// <media:title>, <media:description> and <media:category>, replacing the
+// item's own.
+type Media struct {
+	Thumbnails  []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Contents    []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Groups      []Media          `xml:"http://search.yahoo.com/mrss/ group"`
+	Title       string           `xml:"http://search.yahoo.com/mrss/ title"`
+	Description string           `xml:"http://search.yahoo.com/mrss/ description"`
+	Categories  []string         `xml:"http://search.yahoo.com/mrss/ category"`
+}
+
+// MediaThumbnail is a <media:thumbnail> image
+type MediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// MediaContent is a <media:content> object, which may be an image itself or
+// carry thumbnails of, say, a video
+type MediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`   // MIME type (e.g. "image/jpeg")
+	Medium     string           `xml:"medium,attr"` // "image", "video", etc.
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// isImage reports whether the content is an image, by medium or MIME type.
+func (c MediaContent) isImage() bool {
+	return strings.EqualFold(c.Medium, "image") || strings.HasPrefix(strings.ToLower(c.Type), "image/")
+}
+
+// imageURL returns the URL of the image best representing the item: a
+// thumbnail, then image content, then a thumbnail of other content, looking
+// inside media:group last. It returns "" when there is none.
+func (m Media) imageURL() string {
+	for _, thumb := range m.Thumbnails {
+		if u := strings.TrimSpace(thumb.URL); u != "" {
+			return u
+		}
+	}
+	for _, content := range m.Contents {
+		if u := strings.TrimSpace(content.URL); u != "" && content.isImage() {
+			return u
+		}
+	}
+	for _, content := range m.Contents {
+		if u := (Media{Thumbnails: content.Thumbnails}).imageURL(); u != "" {
+			return u
+		}
+	}
+	for _, group := range m.Groups {
+		if u := group.imageURL(); u != "" {
+			return u
+		}
+	}
+	return ""
+}
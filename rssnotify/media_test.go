@@ -0,0 +1,104 @@
+package rssnotify
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseFeedMediaImages(t *testing.T) {
+	body := []byte(`<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Example</title>
+    <item><title>Thumbnail</title><link>https://example.com/1</link><media:thumbnail url="https://example.com/1.jpg"/></item>
+    <item><title>Image content</title><link>https://example.com/2</link><content:encoded>Body</content:encoded>
+      <media:content url="https://example.com/2.mp4" type="video/mp4"/>
+      <media:content url="https://example.com/2.png" medium="image"/></item>
+    <item><title>Video thumbnail</title><link>https://example.com/3</link>
+      <media:content url="https://example.com/3.mp4" type="video/mp4"><media:thumbnail url="https://example.com/3.jpg"/></media:content></item>
+    <item><title>Plain</title><link>https://example.com/4</link></item>
+  </channel>
+</rss>`)
+
+	items, _, err := parseFeed(body)
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	want := []string{"https://example.com/1.jpg", "https://example.com/2.png", "https://example.com/3.jpg", ""}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for i, item := range items {
+		if got := item.imageURL(); got != want[i] {
+			t.Errorf("item %d image = %q, want %q", i, got, want[i])
+		}
+	}
+	if items[1].Content != "Body" {
+		t.Errorf("content:encoded = %q, want it unaffected by media:content", items[1].Content)
+	}
+}
+
+func TestParseFeedMediaKeepsItemFields(t *testing.T) {
+	body, err := os.ReadFile("testdata/media.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatalf("filterRSSEntries: %v", err)
+	}
+	want := []FilteredEntry{
+		{Title: "Registry raises prices", Description: "Prices rise again.", ImageURL: "https://domainincite.com/1.jpg"},
+		{Title: "New gTLD launches", Description: "Another launch.", ImageURL: "https://domainincite.com/2.jpg"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		got := entries[i]
+		if got.Title != w.Title || got.Description != w.Description || got.ImageURL != w.ImageURL {
+			t.Errorf("entry %d = %q / %q / %q, want %q / %q / %q",
+				i, got.Title, got.Description, got.ImageURL, w.Title, w.Description, w.ImageURL)
+		}
+	}
+}
+
+func TestParseFeedAtomMediaGroup(t *testing.T) {
+	body := []byte(`<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+  <title>Example</title>
+  <entry>
+    <title>Video</title><link href="https://example.com/1"/>
+    <content type="text">Body</content>
+    <media:group><media:content url="https://example.com/1.swf" type="application/x-shockwave-flash"/><media:thumbnail url="https://example.com/1.jpg"/></media:group>
+  </entry>
+</feed>`)
+
+	items, _, err := parseFeed(body)
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("got %d items, want 1", len(items))
+	}
+	if got := items[0].imageURL(); got != "https://example.com/1.jpg" {
+		t.Errorf("image = %q, want the media:group thumbnail", got)
+	}
+	if items[0].Content != "Body" {
+		t.Errorf("content = %q, want the Atom content", items[0].Content)
+	}
+}
+
+func TestBuildSlackMessageEntryImage(t *testing.T) {
+	entries := []FilteredEntry{
+		{Title: "Pictured", Link: "https://example.com/1", ImageURL: "https://example.com/1.jpg", FaviconURL: "https://example.com/favicon.ico"},
+		{Title: "Favicon only", Link: "https://example.com/2", FaviconURL: "https://example.com/favicon.ico"},
+	}
+
+	msg := buildSlackMessage(entries, slackOptions{Messages: englishMessages, Layout: layoutEntriesOnly})
+	if got := msg.Blocks[0].Accessory; got == nil || got.ImageURL != "https://example.com/1.jpg" || got.AltText != "Pictured" {
+		t.Errorf("accessory = %+v, want the article image", got)
+	}
+	if got := msg.Blocks[1].Accessory; got != nil {
+		t.Errorf("accessory = %+v, want none without an image or SLACK_SHOW_FAVICON", got)
+	}
+}
@@ -0,0 +1,142 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultLocale is used when LOCALE is unset or not found in the messages file.
+const defaultLocale = "en"
+
+// messages holds the user-facing strings of the digest for a single locale.
+// Entry titles are never translated.
+//
+// Placeholders: {count} is the number of entries, {first} is a Slack link to
+// the first entry, {label} and {emoji} are the digest name and its emoji. In
+// the relative publication dates {count} is the entry's age in that unit.
+type messages struct {
+	Header   string `json:"header"`
+	Summary  string `json:"summary"`
+	Fallback string `json:"fallback"`
+	Empty    string `json:"empty"` // Heartbeat text when there are no entries
+
+	// Relative publication dates under PUBLISHED_DISPLAY_FORMAT=relative.
+	JustNow    string `json:"just_now"`
+	MinutesAgo string `json:"minutes_ago"`
+	HoursAgo   string `json:"hours_ago"`
+	DaysAgo    string `json:"days_ago"`
+
+	// Section headings of a DIGEST_MODE=diff digest.
+	DiffNew  string `json:"diff_new"`
+	DiffGone string `json:"diff_gone"`
+
+	// Set from SLACK_HEADER_TEXT and SLACK_HEADER_EMOJI rather than bundles.
+	Label string `json:"-"`
+	Emoji string `json:"-"`
+}
+
+// englishMessages are the built-in defaults, also used to fill in any keys a
+// locale bundle leaves out.
+var englishMessages = messages{
+	Header:   "{emoji} {label}",
+	Summary:  "*{count}* new articles",
+	Fallback: "{label}: {count} new articles. First: {first}",
+	Empty:    "No new DNS articles today.",
+
+	JustNow:    "just now",
+	MinutesAgo: "{count}m ago",
+	HoursAgo:   "{count}h ago",
+	DaysAgo:    "{count}d ago",
+
+	DiffNew:  "➕ new",
+	DiffGone: "➖ gone",
+
+	Label: "Daily DNS News Digest (Domain Incite)",
+	Emoji: "📰",
+}
+
+// loadMessages returns the message bundle for locale from the JSON file at
+// path, which maps locale codes to bundles, e.g.
+//
+//	{"de": {"header": "📰 Tägliche DNS-Nachrichten"}}
+//
+// An empty path or locale returns the English defaults.
+func loadMessages(path, locale string) (messages, error) {
+	locale = strings.TrimSpace(locale)
+	if path == "" || locale == "" || locale == defaultLocale {
+		return englishMessages, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return englishMessages, fmt.Errorf("error reading messages file: %w", err)
+	}
+
+	var bundles map[string]messages
+	if err := json.Unmarshal(data, &bundles); err != nil {
+		return englishMessages, fmt.Errorf("error parsing messages file: %w", err)
+	}
+
+	bundle, ok := bundles[locale]
+	if !ok {
+		warnf("Warning: locale %q not found in %s, using %s\n", locale, path, defaultLocale)
+		return englishMessages, nil
+	}
+
+	if bundle.Header == "" {
+		bundle.Header = englishMessages.Header
+	}
+	if bundle.Summary == "" {
+		bundle.Summary = englishMessages.Summary
+	}
+	if bundle.Fallback == "" {
+		bundle.Fallback = englishMessages.Fallback
+	}
+	if bundle.Empty == "" {
+		bundle.Empty = englishMessages.Empty
+	}
+	if bundle.JustNow == "" {
+		bundle.JustNow = englishMessages.JustNow
+	}
+	if bundle.MinutesAgo == "" {
+		bundle.MinutesAgo = englishMessages.MinutesAgo
+	}
+	if bundle.HoursAgo == "" {
+		bundle.HoursAgo = englishMessages.HoursAgo
+	}
+	if bundle.DaysAgo == "" {
+		bundle.DaysAgo = englishMessages.DaysAgo
+	}
+	if bundle.DiffNew == "" {
+		bundle.DiffNew = englishMessages.DiffNew
+	}
+	if bundle.DiffGone == "" {
+		bundle.DiffGone = englishMessages.DiffGone
+	}
+	bundle.Label = englishMessages.Label
+	bundle.Emoji = englishMessages.Emoji
+	return bundle, nil
+}
+
+// render substitutes the placeholders in template. Surrounding space is
+// trimmed so an empty emoji leaves no gap.
+func (m messages) render(template string, count int, first string) string {
+	return strings.TrimSpace(strings.NewReplacer(
+		"{count}", strconv.Itoa(count),
+		"{first}", first,
+		"{label}", m.Label,
+		"{emoji}", m.Emoji,
+	).Replace(template))
+}
+
+// age renders a relative publication date from template, or from the
+// English fallback when the bundle is incomplete (e.g. zero messages).
+func (m messages) age(template, fallback string, n int) string {
+	if template == "" {
+		template = fallback
+	}
+	return m.render(template, n, "")
+}
@@ -0,0 +1,69 @@
+package rssnotify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.json")
+	bundles := `{
+		"de": {"header": "{emoji} Tägliche DNS-Nachrichten", "summary": "*{count}* neue Artikel"},
+		"fr": {"empty": "Aucun nouvel article DNS aujourd'hui."}
+	}`
+	if err := os.WriteFile(path, []byte(bundles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	de, err := loadMessages(path, " de ")
+	if err != nil {
+		t.Fatalf("loadMessages: %v", err)
+	}
+	if got := de.render(de.Header, 0, ""); got != "📰 Tägliche DNS-Nachrichten" {
+		t.Errorf("header = %q", got)
+	}
+	if got := de.render(de.Summary, 3, ""); got != "*3* neue Artikel" {
+		t.Errorf("summary = %q", got)
+	}
+	if de.Fallback != englishMessages.Fallback || de.Empty != englishMessages.Empty {
+		t.Errorf("missing keys = %q, %q, want the English defaults", de.Fallback, de.Empty)
+	}
+
+	for _, locale := range []string{"", "en", "es"} { // Unset, default and unknown
+		msgs, err := loadMessages(path, locale)
+		if err != nil || msgs != englishMessages {
+			t.Errorf("loadMessages(%q) = %+v, %v, want the English messages", locale, msgs, err)
+		}
+	}
+	if msgs, err := loadMessages("", "de"); err != nil || msgs != englishMessages {
+		t.Errorf("loadMessages without a file = %+v, %v, want the English messages", msgs, err)
+	}
+}
+
+func TestLoadMessagesErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := loadMessages(filepath.Join(dir, "missing.json"), "de"); err == nil {
+		t.Error("expected an error for a missing messages file")
+	}
+	path := filepath.Join(dir, "bad.json")
+	os.WriteFile(path, []byte(`{"de": `), 0o600)
+	if _, err := loadMessages(path, "de"); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestLocalizedSlackMessage(t *testing.T) {
+	msgs := englishMessages
+	msgs.Header = "{emoji} Tägliche DNS-Nachrichten"
+	msgs.Fallback = "{count} neue DNS-Artikel. Erster: {first}"
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+
+	msg := buildSlackMessage(entries, slackOptions{Messages: msgs})
+	if got := msg.Blocks[0].Text.Text; got != "📰 Tägliche DNS-Nachrichten" {
+		t.Errorf("header = %q", got)
+	}
+	if want := "1 neue DNS-Artikel. Erster: <https://domainincite.com/1|Registry raises prices>"; msg.Text != want {
+		t.Errorf("fallback = %q, want %q", msg.Text, want)
+	}
+}
@@ -0,0 +1,81 @@
+package rssnotify
+
+import (
+	"net/url"
+	"strings"
+)
+
+// LINK_NORMALIZATION values selecting how links are normalized before they
+// are compared for de-duplication and recorded in the state file.
+const (
+	normalizeOff        = "off"        // Links are compared as given
+	normalizeBasic      = "basic"      // Case, default ports, fragments and trailing slashes are ignored (the default)
+	normalizeAggressive = "aggressive" // As basic, also ignoring http vs https, "www." and tracking parameters
+)
+
+// parseLinkNormalization validates a LINK_NORMALIZATION value, falling back
+// to normalizeBasic.
+func parseLinkNormalization(value string) string {
+	switch level := strings.ToLower(strings.TrimSpace(value)); level {
+	case "":
+		return normalizeBasic
+	case normalizeOff, normalizeBasic, normalizeAggressive:
+		return level
+	default:
+		warnf("Warning: unknown LINK_NORMALIZATION %q, using %s\n", value, normalizeBasic)
+		return normalizeBasic
+	}
+}
+
+// trackingParams are query parameters added for analytics that don't change
+// the page, dropped by normalizeAggressive along with any "utm_" parameter.
+var trackingParams = map[string]bool{"fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true}
+
+// normalizeLink returns the form of link compared under level, so that e.g.
+// "http://www.example.com/a/" and "https://example.com/a" match under
+// normalizeAggressive. An empty level means normalizeBasic. Links that don't
+// parse as absolute URLs are only trimmed.
+func normalizeLink(link, level string) string {
+	link = strings.TrimSpace(link)
+	if level == normalizeOff {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Fragment, u.RawFragment = "", ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+
+	if level == normalizeAggressive {
+		if u.Scheme == "http" {
+			u.Scheme = "https"
+		}
+		host = strings.TrimPrefix(host, "www.")
+		if u.RawQuery != "" {
+			query := u.Query()
+			for name := range query {
+				if strings.HasPrefix(strings.ToLower(name), "utm_") || trackingParams[strings.ToLower(name)] {
+					query.Del(name)
+				}
+			}
+			u.RawQuery = query.Encode() // Also sorts the parameters
+		}
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	return u.String()
+}
@@ -0,0 +1,76 @@
+package rssnotify
+
+import "testing"
+
+func TestNormalizeLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		link  string
+		level string
+		want  string
+	}{
+		{"off keeps the link", " HTTP://WWW.Example.com:80/a/#top ", normalizeOff, "HTTP://WWW.Example.com:80/a/#top"},
+		{"lowercases scheme and host", "HTTPS://Example.COM/Path", normalizeBasic, "https://example.com/Path"},
+		{"drops the default http port", "http://example.com:80/a", normalizeBasic, "http://example.com/a"},
+		{"drops the default https port", "https://example.com:443/a", normalizeBasic, "https://example.com/a"},
+		{"keeps other ports", "https://example.com:8443/a", normalizeBasic, "https://example.com:8443/a"},
+		{"drops the fragment", "https://example.com/a#comments", normalizeBasic, "https://example.com/a"},
+		{"drops trailing slashes", "https://example.com/a//", normalizeBasic, "https://example.com/a"},
+		{"empty level is basic", "https://example.com/a/", "", "https://example.com/a"},
+		{"basic keeps the scheme", "http://www.example.com/a", normalizeBasic, "http://www.example.com/a"},
+		{"basic keeps tracking parameters", "https://example.com/a?utm_source=rss", normalizeBasic, "https://example.com/a?utm_source=rss"},
+		{"aggressive upgrades http", "http://example.com/a", normalizeAggressive, "https://example.com/a"},
+		{"aggressive drops www", "https://www.example.com/a", normalizeAggressive, "https://example.com/a"},
+		{"aggressive drops tracking parameters", "https://example.com/a?utm_source=rss&id=7&fbclid=x&UTM_Medium=y", normalizeAggressive, "https://example.com/a?id=7"},
+		{"aggressive sorts parameters", "https://example.com/a?b=2&a=1", normalizeAggressive, "https://example.com/a?a=1&b=2"},
+		{"aggressive combines every rule", "http://www.example.com:80/a/", normalizeAggressive, "https://example.com/a"},
+		{"keeps IPv6 hosts", "http://[::1]:80/a/", normalizeBasic, "http://[::1]/a"},
+		{"relative links are only trimmed", " /a/ ", normalizeAggressive, "/a/"},
+	}
+	for _, tt := range tests {
+		if got := normalizeLink(tt.link, tt.level); got != tt.want {
+			t.Errorf("%s: normalizeLink(%q, %q) = %q, want %q", tt.name, tt.link, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestDedupeEntriesNormalizesLinks(t *testing.T) {
+	entries := []FilteredEntry{
+		{Title: "first", Link: "http://www.example.com/a/"},
+		{Title: "second", Link: "https://example.com/a"},
+	}
+	if got := dedupeEntries(entries, dedupGUID, normalizeBasic); len(got) != 2 {
+		t.Errorf("basic kept %d entries, want both", len(got))
+	}
+	if got := dedupeEntries(entries, dedupGUID, normalizeAggressive); len(got) != 1 || got[0].Title != "first" {
+		t.Errorf("aggressive kept %+v, want only the first", got)
+	}
+}
+
+func TestSeenKeyStoresNormalizedLinks(t *testing.T) {
+	entry := FilteredEntry{Link: "http://www.example.com/a/?utm_source=rss", FeedURL: "https://example.com/feed"}
+	if got := entry.seenKey(seenKeyLink, dedupGUID, normalizeAggressive); got != "https://example.com/a" {
+		t.Errorf("seenKey = %q, want the normalized link", got)
+	}
+
+	seen := seenEntries{}
+	seen.add(entry.FeedURL, entry.seenKey(seenKeyGUID, dedupGUID, normalizeAggressive))
+	later := FilteredEntry{Link: "https://example.com/a", FeedURL: entry.FeedURL}
+	if unseen := filterUnseen([]FilteredEntry{later}, seen, seenKeyGUID, dedupGUID, normalizeAggressive); len(unseen) != 0 {
+		t.Errorf("got %+v, want the renormalized link recognized as seen", unseen)
+	}
+
+	// State recorded before normalization holds the link as given.
+	legacy := seenEntries{entry.FeedURL: {"https://example.com/a/": true}}
+	if unseen := filterUnseen([]FilteredEntry{{Link: "https://example.com/a/", FeedURL: entry.FeedURL}}, legacy, seenKeyLink, dedupGUID, normalizeBasic); len(unseen) != 0 {
+		t.Errorf("got %+v, want the un-normalized stored link still matched", unseen)
+	}
+}
+
+func TestParseLinkNormalization(t *testing.T) {
+	for value, want := range map[string]string{"": normalizeBasic, " Aggressive ": normalizeAggressive, "off": normalizeOff, "bogus": normalizeBasic} {
+		if got := parseLinkNormalization(value); got != want {
+			t.Errorf("parseLinkNormalization(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
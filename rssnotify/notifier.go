@@ -0,0 +1,233 @@
+package rssnotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Notifier delivers a digest of entries to a chat service. An empty digest
+// only produces a message when NOTIFY_ON_EMPTY asks for a heartbeat. The
+// notifiers in this package work with zero Options, rendering the English
+// digest with the default layout.
+type Notifier interface {
+	Send(ctx context.Context, entries []FilteredEntry) error
+}
+
+// notifierName normalizes a NOTIFIER value, defaulting to "slack".
+func notifierName(value string) string {
+	if name := strings.ToLower(strings.TrimSpace(value)); name != "" {
+		return name
+	}
+	return "slack"
+}
+
+// newNotifier returns the notifier selected by cfg, defaulting to Slack.
+func newNotifier(cfg Config, opts slackOptions, report *runReport) (Notifier, error) {
+	switch notifierName(cfg.Notifier) {
+	case "slack":
+		return SlackNotifier{WebhookURL: cfg.SlackWebhookURL, Options: opts, HTTP: cfg.notifierOptions("SLACK"), Report: report}, nil
+	case "discord":
+		return DiscordNotifier{WebhookURL: cfg.DiscordWebhookURL, Options: opts, HTTP: cfg.notifierOptions("DISCORD"), Report: report}, nil
+	case "teams":
+		return TeamsNotifier{WebhookURL: cfg.TeamsWebhookURL, Options: opts, HTTP: cfg.notifierOptions("TEAMS"), Report: report}, nil
+	case "google-chat":
+		return GoogleChatNotifier{WebhookURL: cfg.GoogleChatWebhookURL, Options: opts, HTTP: cfg.notifierOptions("GOOGLE_CHAT"), Report: report}, nil
+	case "webhook":
+		return WebhookNotifier{URL: cfg.GenericWebhookURL, Options: opts, HTTP: cfg.notifierOptions("GENERIC_WEBHOOK"), Report: report}, nil
+	default:
+		return nil, fmt.Errorf("unknown NOTIFIER %q, expected slack, discord, teams, google-chat or webhook", cfg.Notifier)
+	}
+}
+
+// newNotifiers returns every notifier a run delivers to: the one selected by
+// NOTIFIER, plus Google Chat and the generic webhook whenever their URLs are
+// set. Slack, the default, is left out when it isn't configured but one of
+// those is, so a Google Chat only setup doesn't fail on Slack.
+func newNotifiers(cfg Config, opts slackOptions, report *runReport) ([]Notifier, error) {
+	primary, err := newNotifier(cfg, opts, report)
+	if err != nil {
+		return nil, err
+	}
+	name := notifierName(cfg.Notifier)
+	googleChat := cfg.GoogleChatWebhookURL != "" && name != "google-chat"
+	webhook := cfg.GenericWebhookURL != "" && name != "webhook"
+
+	var notifiers []Notifier
+	if slackUnset := name == "slack" && cfg.SlackWebhookURL == "" && opts.BotToken == ""; !slackUnset || (!googleChat && !webhook) {
+		notifiers = append(notifiers, primary)
+	}
+	if googleChat {
+		notifiers = append(notifiers, GoogleChatNotifier{WebhookURL: cfg.GoogleChatWebhookURL, Options: opts, HTTP: cfg.notifierOptions("GOOGLE_CHAT"), Report: report})
+	}
+	if webhook {
+		notifiers = append(notifiers, WebhookNotifier{URL: cfg.GenericWebhookURL, Options: opts, HTTP: cfg.notifierOptions("GENERIC_WEBHOOK"), Report: report})
+	}
+	return notifiers, nil
+}
+
+// sinkID names the sink n delivers to, keying its seen entries under
+// DEDUP_SCOPE=sink. It is the notifier's name, as in the run report, so
+// rotating a webhook URL doesn't make the sink new.
+func sinkID(n Notifier) string {
+	switch n.(type) {
+	case SlackNotifier:
+		return "slack"
+	case DiscordNotifier:
+		return "discord"
+	case TeamsNotifier:
+		return "teams"
+	case GoogleChatNotifier:
+		return "google-chat"
+	case WebhookNotifier:
+		return "webhook"
+	default:
+		return fmt.Sprintf("%T", n)
+	}
+}
+
+// slackWebhookHost is the host Slack serves incoming webhooks from.
+const slackWebhookHost = "hooks.slack.com"
+
+// validateWebhookURL checks that raw, the value of the named setting, is an
+// https URL and, when host is non-empty, that it points at that host. This
+// reports a misconfigured webhook before any feed is fetched rather than as a
+// failed POST.
+func validateWebhookURL(name, raw, host string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("invalid %s: scheme must be https, got %q", name, u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid %s: missing host", name)
+	}
+	if host != "" && !strings.EqualFold(u.Hostname(), host) {
+		return fmt.Errorf("invalid %s: host must be %s, got %q (use -skip-slack-host-check for proxies)", name, host, u.Hostname())
+	}
+	return nil
+}
+
+// SlackNotifier delivers the digest to Slack, either scheduled, as a canvas
+// or threaded via the bot-token API, as workflow variables, or as a Block Kit
+// message via the webhook.
+type SlackNotifier struct {
+	WebhookURL string
+	Options    slackOptions
+	HTTP       notifierOptions // Overrides of the run's timeout and retries
+	Report     *runReport
+}
+
+// Send delivers entries using the first configured Slack mode. The heartbeat
+// and dry runs always take the Block Kit path, so dry-run payloads are
+// printed rather than sent.
+func (n SlackNotifier) Send(ctx context.Context, entries []FilteredEntry) error {
+	n.Options = n.Options.withDefaults()
+	ctx = n.HTTP.apply(ctx)
+	blockKitOnly := n.Options.DryRun || len(entries) == 0
+
+	botToken, channel, sendAt := n.Options.BotToken, n.Options.Channel, n.Options.SendAt
+	if !blockKitOnly && botToken != "" && channel != "" && sendAt != "" {
+		deliveryStart := time.Now()
+		now := deliveryStart
+		if n.Options.Location != nil {
+			now = now.In(n.Options.Location) // A daily HH:MM is in DISPLAY_TIMEZONE
+		}
+		postAt, err := parseSendAt(sendAt, now)
+		if err == nil {
+			opts := n.Options
+			opts.Now = postAt // Relative dates as of delivery
+			// Stagger overflow messages a second apart so they arrive in order.
+			for i, chunk := range splitSlackMessage(buildSlackMessage(entries, opts)) {
+				if err = scheduleSlackMessage(ctx, botToken, channel, postAt.Add(time.Duration(i)*time.Second), chunk); err != nil {
+					break
+				}
+			}
+		}
+		n.Report.recordDelivery("slack-scheduled", len(entries), time.Since(deliveryStart), err)
+		if err != nil {
+			return fmt.Errorf("error scheduling Slack notification: %w", err)
+		}
+		return nil
+	}
+
+	// Without a webhook, a canvas that can't be updated falls back to posting
+	// to the channel with the bot token.
+	postToChannel := false
+	if !blockKitOnly && n.Options.Canvas {
+		if botToken == "" || channel == "" {
+			return fmt.Errorf("SLACK_CANVAS requires SLACK_BOT_TOKEN and SLACK_CHANNEL")
+		}
+		deliveryStart := time.Now()
+		err := sendCanvasToSlack(ctx, botToken, channel, entries, n.Options)
+		n.Report.recordDelivery("slack-canvas", len(entries), time.Since(deliveryStart), err)
+		if err == nil {
+			return nil
+		}
+		warnf("Warning: Slack canvas unavailable, posting the digest as messages instead: %v\n", err)
+		postToChannel = n.WebhookURL == ""
+	}
+
+	if !blockKitOnly && n.Options.Threaded {
+		if botToken == "" || channel == "" {
+			return fmt.Errorf("SLACK_THREADED requires SLACK_BOT_TOKEN and SLACK_CHANNEL")
+		}
+		deliveryStart := time.Now()
+		err := sendThreadedToSlack(ctx, botToken, channel, entries, n.Options)
+		n.Report.recordDelivery("slack-threaded", len(entries), time.Since(deliveryStart), err)
+		if err != nil {
+			return fmt.Errorf("error sending threaded Slack notification: %w", err)
+		}
+		return nil
+	}
+
+	if postToChannel {
+		deliveryStart := time.Now()
+		err := postToSlackChannel(ctx, botToken, channel, entries, n.Options)
+		n.Report.recordDelivery("slack-api", len(entries), time.Since(deliveryStart), err)
+		if err != nil {
+			return fmt.Errorf("error posting Slack notification: %w", err)
+		}
+		return nil
+	}
+
+	if !blockKitOnly && n.Options.Format == slackFormatWorkflow {
+		deliveryStart := time.Now()
+		err := sendWorkflowToSlack(ctx, n.WebhookURL, entries, n.Options.WorkflowVariables)
+		n.Report.recordDelivery("slack-workflow", len(entries), time.Since(deliveryStart), err)
+		if err != nil {
+			return fmt.Errorf("error sending Slack workflow notification: %w", err)
+		}
+		return nil
+	}
+
+	deliveryStart := time.Now()
+	err := sendNotificationToSlack(ctx, n.WebhookURL, entries, n.Options)
+	n.Report.recordDelivery("slack", len(entries), time.Since(deliveryStart), err)
+	if err != nil {
+		return fmt.Errorf("error sending Slack notification: %w", err)
+	}
+	return nil
+}
+
+// payloadOut is where dry runs print payloads. Tests replace it.
+var payloadOut io.Writer = os.Stdout
+
+// printPayload writes payload to stdout as indented JSON instead of posting
+// it, so dry runs show exactly what would have been sent.
+func printPayload(_ context.Context, _ string, payload any) (string, error) {
+	enc := json.NewEncoder(payloadOut)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(payload); err != nil {
+		return "", fmt.Errorf("error marshalling payload to JSON: %w", err)
+	}
+	return "ok", nil
+}
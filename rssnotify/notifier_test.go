@@ -0,0 +1,136 @@
+package rssnotify
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		raw, host string
+		valid     bool
+	}{
+		{"https://hooks.slack.com/services/T000/B000/XXXX", slackWebhookHost, true},
+		{"https://HOOKS.slack.com/services/T000/B000/XXXX", slackWebhookHost, true},
+		{"hooks.slack.com/services/T000/B000/XXXX", slackWebhookHost, false},
+		{"http://hooks.slack.com/services/T000/B000/XXXX", slackWebhookHost, false},
+		{"https://hoks.slack.com/services/T000/B000/XXXX", slackWebhookHost, false},
+		{"https://slack-proxy.internal/services/T000", slackWebhookHost, false},
+		{"https://slack-proxy.internal/services/T000", "", true},
+		{"https:///services/T000", "", false},
+		{"https://hooks.slack.com/%zz", slackWebhookHost, false},
+	}
+	for _, tt := range tests {
+		err := validateWebhookURL("SLACK_WEBHOOK_URL", tt.raw, tt.host)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateWebhookURL(%q, %q) = %v, want valid %v", tt.raw, tt.host, err, tt.valid)
+		}
+	}
+}
+
+func TestNewNotifiers(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"default", Config{}, []string{"rssnotify.SlackNotifier"}},
+		{"slack and google chat", Config{SlackWebhookURL: "https://hooks.slack.com/x", GoogleChatWebhookURL: "https://chat.googleapis.com/x"},
+			[]string{"rssnotify.SlackNotifier", "rssnotify.GoogleChatNotifier"}},
+		{"google chat only", Config{GoogleChatWebhookURL: "https://chat.googleapis.com/x", GenericWebhookURL: "https://example.com/hook"},
+			[]string{"rssnotify.GoogleChatNotifier", "rssnotify.WebhookNotifier"}},
+		{"selected google chat", Config{Notifier: "google-chat", GoogleChatWebhookURL: "https://chat.googleapis.com/x"},
+			[]string{"rssnotify.GoogleChatNotifier"}},
+		{"discord and webhook", Config{Notifier: "discord", GenericWebhookURL: "https://example.com/hook"},
+			[]string{"rssnotify.DiscordNotifier", "rssnotify.WebhookNotifier"}},
+	}
+	for _, tt := range tests {
+		notifiers, err := newNotifiers(tt.cfg, slackOptions{}, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, n := range notifiers {
+			got = append(got, fmt.Sprintf("%T", n))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: notifiers = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, err := newNotifiers(Config{Notifier: "irc"}, slackOptions{}, nil); err == nil {
+		t.Error("expected an error for an unknown NOTIFIER")
+	}
+}
+
+func TestNotifierOptionsOverrideRunSettings(t *testing.T) {
+	delay := retryBaseDelay
+	retryBaseDelay = 0
+	t.Cleanup(func() { retryBaseDelay = delay })
+	t.Setenv("DISCORD_MAX_RETRIES", "1")
+	t.Setenv("DISCORD_HTTP_TIMEOUT", "5")
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.URL.Path]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	notifiers, err := newNotifiers(Config{Notifier: "discord", DiscordWebhookURL: srv.URL + "/discord", GenericWebhookURL: srv.URL + "/webhook"}, slackOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	global := runSettings{MaxRetries: 3, WebhookTimeout: time.Minute}
+	ctx := withSettings(t.Context(), global)
+	if got := settingsFrom(notifiers[0].(DiscordNotifier).HTTP.apply(ctx)); got.MaxRetries != 1 || got.WebhookTimeout != 5*time.Second {
+		t.Errorf("discord settings = %+v, want its own timeout and retries", got)
+	}
+	if got := settingsFrom(notifiers[1].(WebhookNotifier).HTTP.apply(ctx)); got != global {
+		t.Errorf("webhook settings = %+v, want the global %+v", got, global)
+	}
+
+	entries := []FilteredEntry{{Title: "Entry", Link: "https://domainincite.com/1"}}
+	for _, n := range notifiers {
+		if err := n.Send(ctx, entries); err == nil {
+			t.Errorf("%T: expected an error from the failing webhook", n)
+		}
+	}
+	if attempts["/discord"] != 1 || attempts["/webhook"] != 3 {
+		t.Errorf("attempts = %v, want 1 for discord (DISCORD_MAX_RETRIES) and 3 for the webhook (HTTP_MAX_RETRIES)", attempts)
+	}
+}
+
+func TestRunSelfTestUsesConfiguredNotifiers(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(data), selfTestEntry.Title) {
+			t.Errorf("%s payload = %s, want the self-test entry", r.URL.Path, data)
+		}
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := Config{Notifier: "discord", DiscordWebhookURL: srv.URL + "/discord", GoogleChatWebhookURL: srv.URL + "/chat"}
+	if err := runSelfTest(t.Context(), resolveConfig(t, cfg), nil); err != nil {
+		t.Fatalf("runSelfTest: %v", err)
+	}
+	if !slices.Equal(paths, []string{"/discord", "/chat"}) {
+		t.Errorf("posted to %v, want Discord and Google Chat", paths)
+	}
+
+	if err := runSelfTest(t.Context(), resolveConfig(t, Config{}), nil); err == nil {
+		t.Error("expected an error with no notifier configured")
+	}
+}
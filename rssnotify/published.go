@@ -0,0 +1,71 @@
+package rssnotify
+
+import (
+	"strings"
+	"time"
+)
+
+// publishedRelative is the PUBLISHED_DISPLAY_FORMAT value showing entry dates
+// as their age, e.g. "3h ago", and the default.
+const publishedRelative = "relative"
+
+// relativeCutoff is the age beyond which relative dates show the day instead.
+const relativeCutoff = 7 * 24 * time.Hour
+
+// parsePublishedFormat validates a PUBLISHED_DISPLAY_FORMAT value, either a
+// Go time layout (e.g. "2006-01-02 15:04 MST") or publishedRelative. The
+// layout is checked by formatting a sample time in loc: one without any
+// layout elements would show the same text for every entry, so it falls back
+// to publishedRelative with a warning.
+func parsePublishedFormat(value string, loc *time.Location) string {
+	layout := strings.TrimSpace(value)
+	if layout == "" || strings.EqualFold(layout, publishedRelative) {
+		return publishedRelative
+	}
+	sample := time.Date(2024, time.June, 1, 9, 0, 0, 0, time.UTC)
+	if loc != nil {
+		sample = sample.In(loc)
+	}
+	formatted := sample.Format(layout)
+	if formatted == layout {
+		warnf("Warning: PUBLISHED_DISPLAY_FORMAT %q has no Go layout elements (e.g. \"2006-01-02 15:04 MST\"), using %s\n", value, publishedRelative)
+		return publishedRelative
+	}
+	debugf("Publication dates will be shown like %q\n", formatted)
+	return layout
+}
+
+// formatPublished renders an entry's publication date in opts.Location under
+// opts.PublishedFormat. Relative dates are measured from opts.Now, or the
+// current time when it is zero, worded by opts.Messages, and those older
+// than relativeCutoff show the day instead.
+func (opts slackOptions) formatPublished(published time.Time) string {
+	if opts.Location != nil {
+		published = published.In(opts.Location)
+	}
+	if !opts.relativeDates() {
+		return published.Format(opts.PublishedFormat)
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	m := opts.Messages
+	switch age := now.Sub(published); {
+	case age < time.Minute:
+		return m.age(m.JustNow, englishMessages.JustNow, 0) // Including dates slightly in the future
+	case age < time.Hour:
+		return m.age(m.MinutesAgo, englishMessages.MinutesAgo, int(age.Minutes()))
+	case age < 24*time.Hour:
+		return m.age(m.HoursAgo, englishMessages.HoursAgo, int(age.Hours()))
+	case age < relativeCutoff:
+		return m.age(m.DaysAgo, englishMessages.DaysAgo, int(age.Hours()/24))
+	default:
+		return published.Format("Jan 2")
+	}
+}
+
+// relativeDates reports whether entry dates are shown as their age.
+func (opts slackOptions) relativeDates() bool {
+	return opts.PublishedFormat == "" || opts.PublishedFormat == publishedRelative
+}
@@ -0,0 +1,100 @@
+package rssnotify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePublishedFormat(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                     publishedRelative,
+		" Relative ":           publishedRelative,
+		"2006-01-02 15:04 MST": "2006-01-02 15:04 MST",
+		"yyyy-mm-dd":           publishedRelative, // No layout elements
+	} {
+		if got := parsePublishedFormat(value, time.UTC); got != want {
+			t.Errorf("parsePublishedFormat(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestFormatPublished(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	now := time.Date(2024, time.June, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		format    string
+		published time.Time
+		want      string
+	}{
+		{"", now.Add(30 * time.Second), "just now"},
+		{publishedRelative, now.Add(-5 * time.Minute), "5m ago"},
+		{publishedRelative, now.Add(-3 * time.Hour), "3h ago"},
+		{publishedRelative, now.Add(-50 * time.Hour), "2d ago"},
+		{publishedRelative, time.Date(2024, time.June, 1, 8, 0, 0, 0, time.UTC), "Jun 1"},
+		{"2006-01-02 15:04 MST", time.Date(2024, time.June, 1, 8, 0, 0, 0, time.UTC), "2024-06-01 09:00 BST"},
+	}
+	for _, tt := range tests {
+		opts := slackOptions{PublishedFormat: tt.format, Location: london, Now: now}
+		if got := opts.formatPublished(tt.published); got != tt.want {
+			t.Errorf("format %q: formatPublished(%v) = %q, want %q", tt.format, tt.published, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPublishedLocalized(t *testing.T) {
+	now := time.Date(2024, time.June, 10, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "messages.json")
+	bundles := `{"de": {"just_now": "gerade eben", "hours_ago": "vor {count} Std."}}`
+	if err := os.WriteFile(path, []byte(bundles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := loadMessages(path, "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := slackOptions{Messages: msgs, Location: time.UTC, Now: now}
+	for published, want := range map[time.Time]string{
+		now:                       "gerade eben",
+		now.Add(-3 * time.Hour):   "vor 3 Std.",
+		now.Add(-5 * time.Minute): "5m ago", // Missing from the bundle
+	} {
+		if got := opts.formatPublished(published); got != want {
+			t.Errorf("formatPublished(%v) = %q, want %q", published, got, want)
+		}
+	}
+}
+
+func TestPublishedFormatAcrossNotifiers(t *testing.T) {
+	entry := FilteredEntry{
+		Title:     "Registry raises prices",
+		Link:      "https://domainincite.com/1",
+		Source:    "Domain Incite",
+		Published: time.Date(2024, time.June, 1, 8, 0, 0, 0, time.UTC),
+	}
+	opts := slackOptions{Messages: englishMessages, PublishedFormat: "2006-01-02", Location: time.UTC}
+
+	if line := formatEntryLine(entry, opts); !strings.HasSuffix(line, " — 2024-06-01") {
+		t.Errorf("Slack line = %q, want the formatted date", line)
+	}
+	if text := buildTeamsMessages([]FilteredEntry{entry}, opts)[0].Attachments[0].Content.Body[1].Text; !strings.HasSuffix(text, " — 2024-06-01") {
+		t.Errorf("Teams text = %q, want the formatted date", text)
+	}
+	if label := buildGoogleChatMessages([]FilteredEntry{entry}, opts)[0].CardsV2[0].Card.Sections[0].Widgets[0].DecoratedText.BottomLabel; label != "2024-06-01" {
+		t.Errorf("Google Chat label = %q, want the formatted date", label)
+	}
+
+	embed := buildDiscordEmbed(entry, opts)
+	if embed.Timestamp != "" || embed.Footer == nil || embed.Footer.Text != "Domain Incite · 2024-06-01" {
+		t.Errorf("Discord embed = %+v, want the formatted date in the footer", embed)
+	}
+	opts.PublishedFormat = publishedRelative
+	if embed := buildDiscordEmbed(entry, opts); embed.Timestamp != "2024-06-01T08:00:00Z" || embed.Footer.Text != "Domain Incite" {
+		t.Errorf("Discord embed = %+v, want the timestamp for relative dates", embed)
+	}
+}
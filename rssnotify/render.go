@@ -0,0 +1,151 @@
+package rssnotify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ANSI escape sequences used by renderPayload on a terminal.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiUnderline = "\x1b[4m"
+	ansiBlue      = "\x1b[34m"
+)
+
+// renderWidth is the width of rendered dividers.
+const renderWidth = 60
+
+var (
+	mrkdwnLink = regexp.MustCompile(`<([^<>|]+)(?:\|([^<>]*))?>`)
+	mrkdwnBold = regexp.MustCompile(`\*([^*\n]+)\*`)
+)
+
+// renderPayload writes Slack messages to payloadOut as a readable
+// approximation of how Slack shows them, for dry runs with -render. Styling
+// is only used when payloadOut is a terminal; other payloads are printed as
+// JSON by printPayload.
+func renderPayload(ctx context.Context, webhookURL string, payload any) (string, error) {
+	msg, ok := payload.(SlackMessage)
+	if !ok {
+		return printPayload(ctx, webhookURL, payload)
+	}
+	if _, err := io.WriteString(payloadOut, renderSlackMessage(msg, isTerminal(payloadOut))); err != nil {
+		return "", fmt.Errorf("error writing preview: %w", err)
+	}
+	return "ok", nil
+}
+
+// isTerminal reports whether w is a character device such as a TTY.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// renderSlackMessage renders msg's blocks and then its attachments, each
+// attachment's lines marked by a bar in its color when styled.
+func renderSlackMessage(msg SlackMessage, styled bool) string {
+	var b strings.Builder
+	for _, block := range msg.Blocks {
+		renderBlock(&b, block, "", styled)
+	}
+	for _, attachment := range msg.Attachments {
+		bar := "│ "
+		if styled {
+			bar = hexColor(attachment.Color) + "│" + ansiReset + " "
+		}
+		for _, block := range attachment.Blocks {
+			renderBlock(&b, block, bar, styled)
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// renderBlock writes block to b, each line starting with prefix.
+func renderBlock(b *strings.Builder, block SlackBlock, prefix string, styled bool) {
+	var lines []string
+	switch block.Type {
+	case "header":
+		if block.Text != nil {
+			lines = append(lines, style(block.Text.Text, ansiBold+ansiUnderline, styled))
+		}
+	case "divider":
+		lines = append(lines, style(strings.Repeat("─", renderWidth), ansiDim, styled))
+	case "section":
+		if block.Text != nil {
+			lines = append(lines, strings.Split(renderMrkdwn(block.Text.Text, styled), "\n")...)
+		}
+		if block.Accessory != nil && block.Accessory.Type == "image" {
+			lines = append(lines, style("[image: "+block.Accessory.AltText+"]", ansiDim, styled))
+		}
+	case "context":
+		var parts []string
+		for _, element := range block.Elements {
+			if element.Type == "image" {
+				parts = append(parts, "["+element.AltText+"]")
+				continue
+			}
+			parts = append(parts, renderMrkdwn(element.Text, false))
+		}
+		lines = append(lines, style(strings.Join(parts, "  "), ansiDim, styled))
+	}
+	for _, line := range lines {
+		b.WriteString(prefix + line + "\n")
+	}
+}
+
+// renderMrkdwn replaces Slack's link and bold markup with ANSI styling, or
+// with plain text showing each link's URL after its label.
+func renderMrkdwn(text string, styled bool) string {
+	text = mrkdwnLink.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mrkdwnLink.FindStringSubmatch(match)
+		target, label := parts[1], parts[2]
+		if special, ok := strings.CutPrefix(target, "!"); ok {
+			return "@" + special // e.g. <!here>
+		}
+		if label == "" {
+			return style(target, ansiBlue+ansiUnderline, styled)
+		}
+		return style(label, ansiBlue+ansiUnderline, styled) + " " + style("("+target+")", ansiDim, styled)
+	})
+	if styled {
+		text = mrkdwnBold.ReplaceAllString(text, ansiBold+"$1"+ansiReset)
+	} else {
+		text = mrkdwnBold.ReplaceAllString(text, "$1")
+	}
+	return html.UnescapeString(text)
+}
+
+// style wraps text in the ANSI code when styled.
+func style(text, code string, styled bool) string {
+	if !styled || text == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// hexColor returns the ANSI 24-bit foreground sequence for a "#rrggbb"
+// color, or ansiDim when it doesn't parse.
+func hexColor(color string) string {
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 6 {
+		return ansiDim
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return ansiDim
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", rgb>>16, rgb>>8&0xff, rgb&0xff)
+}
@@ -0,0 +1,50 @@
+package rssnotify
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRenderPayloadPlainWhenNotATerminal(t *testing.T) {
+	var out bytes.Buffer
+	payloadOut = &out
+	t.Cleanup(func() { payloadOut = os.Stdout })
+
+	entries := []FilteredEntry{{Title: "Registry raises <prices> & fees", Link: "https://domainincite.com/1"}}
+	opts := slackOptions{Messages: englishMessages, DryRun: true, Render: true}
+	if err := sendNotificationToSlack(t.Context(), "", entries, opts); err != nil {
+		t.Fatalf("sendNotificationToSlack: %v", err)
+	}
+
+	got := out.String()
+	if strings.Contains(got, "\x1b[") || strings.Contains(got, `"blocks"`) {
+		t.Errorf("preview = %q, want plain text rather than ANSI codes or JSON", got)
+	}
+	for _, want := range []string{opts.headerText(), strings.Repeat("─", renderWidth), "Registry raises <prices> & fees (https://domainincite.com/1)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("preview = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestRenderSlackMessageStyled(t *testing.T) {
+	msg := SlackMessage{
+		Blocks: []SlackBlock{{Type: "header", Text: &SlackText{Type: "plain_text", Text: "Daily DNS News"}}},
+		Attachments: []SlackAttachment{{Color: "#36a64f", Blocks: []SlackBlock{
+			{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: "<!here> *New:* <https://domainincite.com/1|Registry raises prices>"}},
+		}}},
+	}
+	got := renderSlackMessage(msg, true)
+	for _, want := range []string{
+		ansiBold + ansiUnderline + "Daily DNS News" + ansiReset,
+		"\x1b[38;2;54;166;79m│" + ansiReset,
+		"@here " + ansiBold + "New:" + ansiReset,
+		ansiBlue + ansiUnderline + "Registry raises prices" + ansiReset,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("preview = %q, want it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package rssnotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// runReport is a machine-readable summary of a single run, written to
+// RUN_REPORT_FILE for wrappers to decide on alerting. Every field is always
+// present so the schema stays stable.
+type runReport struct {
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	DurationMS int64            `json:"duration_ms"`
+	Success    bool             `json:"success"`
+	Error      string           `json:"error"`
+	NewEntries int              `json:"new_entries"`
+	Held       int              `json:"held,omitempty"` // Entries GUARD_AGAINST_FLOOD held back instead of sending
+	Feeds      []feedReport     `json:"feeds"`
+	Deliveries []deliveryReport `json:"deliveries"`
+}
+
+// feedReport records the outcome of fetching a single feed.
+type feedReport struct {
+	URL        string `json:"url"`
+	Entries    int    `json:"entries"`
+	DurationMS int64  `json:"duration_ms"`
+	Attempts   int    `json:"attempts"` // Requests made, counting retries
+	Error      string `json:"error"`
+}
+
+// deliveryReport records the outcome of delivering the digest via a single
+// notifier.
+type deliveryReport struct {
+	Notifier   string `json:"notifier"`
+	Entries    int    `json:"entries"`
+	Success    bool   `json:"success"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error"`
+}
+
+// newRunReport starts a report for a run beginning at start.
+func newRunReport(start time.Time) *runReport {
+	return &runReport{
+		StartedAt:  start,
+		Feeds:      []feedReport{},
+		Deliveries: []deliveryReport{},
+	}
+}
+
+// recordFeed adds the outcome of fetching a feed to the report. A nil report
+// records nothing.
+func (r *runReport) recordFeed(result feedResult) {
+	if r == nil {
+		return
+	}
+	r.Feeds = append(r.Feeds, feedReport{
+		URL:        result.URL,
+		Entries:    len(result.Entries),
+		DurationMS: result.Elapsed.Milliseconds(),
+		Attempts:   result.Attempts,
+		Error:      errorString(result.Err),
+	})
+}
+
+// recordDelivery adds the outcome of a notifier's delivery to the report. A
+// nil report, as in a zero-value notifier, records nothing.
+func (r *runReport) recordDelivery(notifier string, entries int, elapsed time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	r.Deliveries = append(r.Deliveries, deliveryReport{
+		Notifier:   notifier,
+		Entries:    entries,
+		Success:    err == nil,
+		DurationMS: elapsed.Milliseconds(),
+		Error:      errorString(err),
+	})
+}
+
+// finish marks the run as complete at end with the run's overall error.
+func (r *runReport) finish(end time.Time, err error) {
+	r.FinishedAt = end
+	r.DurationMS = end.Sub(r.StartedAt).Milliseconds()
+	r.Success = err == nil
+	r.Error = errorString(err)
+}
+
+// write saves the report as JSON to path.
+func (r *runReport) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling run report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing run report: %w", err)
+	}
+	return nil
+}
+
+// runStats counts entries as they move through a run, for the SUMMARY line
+// logged at the end.
+type runStats struct {
+	Fetched    int // Items parsed from the feeds
+	Filtered   int // Items passing the filters
+	Sent       int // Entries delivered by the notifier
+	Duplicates int // Entries dropped as duplicates within a feed
+	Skipped    int // Malformed items skipped while parsing
+}
+
+// runCounter accumulates the counters for a run. Feeds are fetched
+// concurrently, so updates are serialized.
+type runCounter struct {
+	mu    sync.Mutex
+	stats runStats
+}
+
+// add adds delta to the counters. A nil counter counts nothing.
+func (c *runCounter) add(delta runStats) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Fetched += delta.Fetched
+	c.stats.Filtered += delta.Filtered
+	c.stats.Sent += delta.Sent
+	c.stats.Duplicates += delta.Duplicates
+	c.stats.Skipped += delta.Skipped
+}
+
+// snapshot returns the counters so far.
+func (c *runCounter) snapshot() runStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// count adds delta to the run's counter carried by ctx, if any.
+func count(ctx context.Context, delta runStats) {
+	settingsFrom(ctx).Counter.add(delta)
+}
+
+// summary renders the counters as a single greppable line.
+func (s runStats) summary(elapsed time.Duration) string {
+	return fmt.Sprintf("SUMMARY fetched=%d filtered=%d sent=%d duplicates=%d skipped=%d duration=%s",
+		s.Fetched, s.Filtered, s.Sent, s.Duplicates, s.Skipped, elapsed.Round(time.Millisecond))
+}
+
+// errorString returns err's message, or "" for a nil error.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
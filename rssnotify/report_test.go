@@ -0,0 +1,75 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunReport(t *testing.T) {
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 1})
+	t.Setenv("STATE_FILE", filepath.Join(t.TempDir(), "seen.json"))
+	feed := newFeedServer(t, "feed.xml")
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+	slack, _ := newSlackServer(t, http.StatusOK)
+
+	start := time.Now()
+	report := newRunReport(start)
+	cfg := Config{FeedURLs: []string{feed.URL, broken.URL}, SlackWebhookURL: slack.URL}
+	err := run(ctx, feed.Client(), resolveConfig(t, cfg), report)
+	if err == nil {
+		t.Fatal("expected the broken feed to fail the run")
+	}
+	report.finish(start.Add(1500*time.Millisecond), err)
+
+	if report.Success || report.Error == "" || report.DurationMS != 1500 {
+		t.Errorf("report = success %v, error %q, duration %dms", report.Success, report.Error, report.DurationMS)
+	}
+	if len(report.Feeds) != 2 || report.Feeds[0].URL != feed.URL || report.Feeds[0].Entries == 0 || report.Feeds[0].Error != "" || report.Feeds[1].Error == "" {
+		t.Errorf("feeds = %+v, want the working feed's entries and the broken feed's error", report.Feeds)
+	}
+	if report.NewEntries != report.Feeds[0].Entries {
+		t.Errorf("new entries = %d, want %d", report.NewEntries, report.Feeds[0].Entries)
+	}
+	if len(report.Deliveries) != 1 || report.Deliveries[0].Notifier != "slack" || !report.Deliveries[0].Success || report.Deliveries[0].Entries != report.NewEntries {
+		t.Errorf("deliveries = %+v, want one successful Slack delivery", report.Deliveries)
+	}
+}
+
+func TestRunReportWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	report := newRunReport(time.Date(2024, time.June, 1, 9, 0, 0, 0, time.UTC))
+	report.recordDelivery("webhook", 0, 0, errors.New("status 500"))
+	report.finish(report.StartedAt.Add(time.Second), nil)
+	if err := report.write(path); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	// Every field is present, even when empty, so the schema stays stable.
+	for _, key := range []string{"started_at", "finished_at", "duration_ms", "success", "error", "new_entries", "feeds", "deliveries"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("report is missing %q: %s", key, data)
+		}
+	}
+	if feeds, ok := fields["feeds"].([]any); !ok || len(feeds) != 0 {
+		t.Errorf("feeds = %v, want an empty array", fields["feeds"])
+	}
+	delivery := fields["deliveries"].([]any)[0].(map[string]any)
+	if delivery["success"] != false || delivery["error"] != "status 500" {
+		t.Errorf("delivery = %v, want the failure recorded", delivery)
+	}
+}
@@ -0,0 +1,134 @@
+package rssnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultMaxRetries is the number of attempts made when no MaxRetries is set
+// in the context, e.g. because HTTP_MAX_RETRIES is unset.
+const defaultMaxRetries = 3
+
+// retryBaseDelay is the wait before the first retry; it doubles after each
+// subsequent attempt.
+var retryBaseDelay = 500 * time.Millisecond
+
+// doWithRetry calls fn up to the MaxRetries set in ctx (see runSettings)
+// times, backing off exponentially between attempts. Only transient errors returned by fn (see
+// isRetryableError) and 5xx/429 responses are retried; any other error or
+// response is returned to the caller as-is. A 429's Retry-After header
+// replaces the backoff delay for that attempt. Retrying stops once ctx is
+// done. The error after the final attempt reports how many attempts were
+// made.
+func doWithRetry(ctx context.Context, fn func() (*http.Response, error)) (*http.Response, error) {
+	settings := settingsFrom(ctx)
+	attempts := settings.MaxRetries
+	if attempts == 0 {
+		attempts = defaultMaxRetries
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := fn()
+		if settings.Attempts != nil {
+			*settings.Attempts++
+		}
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		if err != nil && !isRetryableError(err) {
+			return nil, err
+		}
+		// A cancelled or expired context fails every later attempt too.
+		// Past the soft deadline no further attempt is started.
+		if attempt == attempts || (err != nil && ctx.Err() != nil) || settings.SoftDeadline.passed() {
+			if err != nil {
+				return nil, fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("giving up after %d attempt(s): received status code %d: %s", attempt, resp.StatusCode, string(body))
+		}
+
+		wait := delay
+		if err != nil {
+			warnf("Attempt %d of %d failed: %v. Retrying in %s...\n", attempt, attempts, err, wait)
+		} else {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusTooManyRequests {
+				wait = retryAfter(resp.Header.Get("Retry-After"), delay)
+			}
+			warnf("Attempt %d of %d received status code %d. Retrying in %s...\n", attempt, attempts, resp.StatusCode, wait)
+		}
+		if err := pause(ctx, wait); err != nil {
+			return nil, fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+		}
+		delay *= 2
+	}
+}
+
+// pause waits for d, returning early with ctx's error once ctx is done.
+func pause(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// maxRetryAfter caps the wait requested by a Retry-After header, so a
+// misbehaving server can't stall the run.
+const maxRetryAfter = time.Minute
+
+// retryAfter returns the wait requested by a Retry-After header value, given
+// in seconds or as an HTTP date, or fallback when it is absent or invalid.
+func retryAfter(value string, fallback time.Duration) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = max(time.Until(at), 0)
+	} else {
+		return fallback
+	}
+	return min(wait, maxRetryAfter)
+}
+
+// isRetryableError reports whether an error returned by a retried call is
+// transient: a timeout, a reset or refused connection, or a truncated body.
+// Anything else, such as a TLS handshake failure, an invalid URL or a body
+// that fails to decompress, would fail the same way on every attempt. Every
+// *url.Error is a net.Error, so only its Timeout is consulted.
+func isRetryableError(err error) bool {
+	if errors.Is(err, errTruncatedResponse) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isRetryableStatus reports whether a response status indicates a transient
+// server-side failure worth retrying.
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
@@ -0,0 +1,2002 @@
+package rssnotify
+
+import (
+	"bytes"           // For creating a buffer from the JSON payload
+	"compress/gzip"   // For decompressing gzip-encoded feeds
+	"context"         // For bounding the whole run with RUN_TIMEOUT_SECONDS
+	"crypto/tls"      // For enforcing a minimum TLS version on feed fetches
+	"encoding/base64" // For encoding basic auth credentials
+	"encoding/json"   // For marshalling Go structs to JSON for Slack
+	"encoding/xml"    // For parsing the RSS feed (XML)
+	"errors"          // For detecting truncated responses
+	"flag"            // For parsing command-line flags
+	"fmt"             // For formatted I/O
+	"html"            // For unescaping HTML entities in links
+	"io"
+	"log"      // For logging messages
+	"log/slog" // For the LOG_LEVEL values
+	"maps"     // For telling whether a canvas was created
+	"net"      // For detecting TLS alerts from the feed server
+	"net/http" // For making HTTP GET and POST requests
+	"net/url"  // For validating GUID permalinks
+	"os"       // For accessing environment variables
+	"reflect"  // For reading the code of a received TLS alert
+	"regexp"   // For matching HTML entities in links
+	"slices"   // For checking whether a feed has dated entries
+	"sort"     // For ordering entries by publication date
+	"strconv"  // For parsing boolean environment variables
+	"strings"  // For string manipulations
+	"sync"     // For fetching feeds concurrently
+	"time"     // For setting HTTP client timeouts
+)
+
+// RSS structure definitions for XML parsing
+type RSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel Channel  `xml:"channel"`
+}
+
+// Channel is the RSS channel
+type Channel struct {
+	XMLName   xml.Name   `xml:"channel"`
+	AtomLinks []AtomLink `xml:"http://www.w3.org/2005/Atom link"` // Declared before Link, see Item
+	Title     string     `xml:"title"`
+	Link      string     `xml:"link"`
+	Items     []Item     `xml:"item"`
+}
+
+// feedHost returns the host of feedURL, or feedURL itself if it can't be
+// parsed.
+func feedHost(feedURL string) string {
+	if u, err := url.Parse(feedURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return feedURL
+}
+
+// faviconURL derives the conventional /favicon.ico URL for the host of link,
+// returning "" when link isn't an absolute http(s) URL.
+func faviconURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/favicon.ico"}).String()
+}
+
+// Item is the individual items
+//
+// NOTE: Namespaced fields must be declared before Link, otherwise the
+// un-namespaced "link" tag would also capture <atom:link> elements.
+type Item struct {
+	XMLName     xml.Name       `xml:"item"`
+	AtomLinks   []AtomLink     `xml:"http://www.w3.org/2005/Atom link"`
+	CommentRSS  string         `xml:"http://wellformedweb.org/CommentAPI/ commentRss"`
+	Content     string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Media                      // media:thumbnail and media:content images
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	GUID        GUID           `xml:"guid"`
+	Description string         `xml:"description"`
+	PubDate     string         `xml:"pubDate"`
+	Categories  []Category     `xml:"category"`
+	Enclosures  []Enclosure    `xml:"enclosure"`
+	Extra       []extraElement `xml:",any"` // Other elements, which DEDUP_FIELD paths can name
+}
+
+// Enclosure is a media file attached to an item (e.g. a podcast episode)
+type Enclosure struct {
+	URL    string `xml:"url,attr" json:"url"`
+	Type   string `xml:"type,attr" json:"type,omitempty"`
+	Length string `xml:"length,attr" json:"length,omitempty"` // Size in bytes
+}
+
+// size returns the enclosure length in bytes, and false when it is missing
+// or invalid.
+func (e Enclosure) size() (int64, bool) {
+	n, err := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// primaryEnclosure returns the item's first enclosure with a URL, or nil.
+func (item Item) primaryEnclosure() *Enclosure {
+	for _, enc := range item.Enclosures {
+		if strings.TrimSpace(enc.URL) != "" {
+			enc.URL = strings.TrimSpace(enc.URL)
+			return &enc
+		}
+	}
+	return nil
+}
+
+// GUID is the item's unique identifier, which may also be its permalink
+type GUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"` // Defaults to "true" when absent
+}
+
+// permalink returns the GUID as a link when it is marked (or defaults) as a
+// permalink and looks like an absolute http(s) URL, otherwise "".
+func (g GUID) permalink() string {
+	if strings.EqualFold(strings.TrimSpace(g.IsPermaLink), "false") {
+		return ""
+	}
+	value := strings.TrimSpace(g.Value)
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return value
+}
+
+// AtomLink is an Atom <link> element, either in an Atom feed or embedded
+// within an RSS item as <atom:link>
+type AtomLink struct {
+	Rel    string `xml:"rel,attr"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// Category structure to handle <![CDATA[...]]> content
+type Category struct {
+	XMLName xml.Name `xml:"category"`    // Category element
+	Data    string   `xml:",cdata"`      // The text content, plain or within CDATA tags
+	Term    string   `xml:"term,attr"`   // Atom-style term attribute
+	Domain  string   `xml:"domain,attr"` // RSS domain attribute, used by some feeds for the category itself
+}
+
+// values returns the category's non-empty trimmed text and attribute values,
+// any of which can match a filter.
+func (cat Category) values() []string {
+	var values []string
+	for _, value := range []string{cat.Data, cat.Term, cat.Domain} {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// FilteredEntry is the filtered entries we want to send
+type FilteredEntry struct {
+	Title       string     `json:"title"`
+	Link        string     `json:"link"`
+	GUID        string     `json:"guid,omitempty"`         // The item's <guid> or Atom <id>, stable across link changes
+	ID          string     `json:"id,omitempty"`           // The item element named by a DEDUP_FIELD path, if any
+	Category    string     `json:"category,omitempty"`     // The configured category the entry matched
+	DiscussLink string     `json:"discuss_link,omitempty"` // Comment thread for the entry, if the feed provides one
+	Enclosure   *Enclosure `json:"enclosure,omitempty"`    // Primary media file attached to the entry
+	FaviconURL  string     `json:"favicon_url,omitempty"`  // Icon identifying the entry's source site
+	ImageURL    string     `json:"image_url,omitempty"`    // Article image from Media RSS, if the feed has one
+	Priority    bool       `json:"priority,omitempty"`     // Matched the priority pattern or categories
+	FeedURL     string     `json:"feed_url,omitempty"`     // The feed the entry came from
+	Source      string     `json:"source,omitempty"`       // The feed's title, used to label entries
+	Author      string     `json:"author,omitempty"`       // The item's author, if the feed names one
+	Published   time.Time  `json:"published,omitzero"`     // Publication date, zero when the feed has none
+	Rank        int        `json:"-"`                      // Position in a dateless feed, 1 for the first, see rankDateless
+	Change      string     `json:"change,omitempty"`       // changeNew or changeGone under DIGEST_MODE=diff
+	Description string     `json:"description,omitempty"`  // Plain-text summary of the article
+}
+
+// pubDateLayouts are tried in order when parsing an item's date. RSS 2.0
+// specifies RFC 822 dates, though many feeds omit the numeric zone; Atom
+// entries carry RFC 3339 timestamps.
+var pubDateLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339}
+
+// published returns the item's parsed publication date. Missing or
+// unparseable dates report false so the entry is shown without one.
+func (item Item) published() (time.Time, bool) {
+	value := strings.TrimSpace(item.PubDate)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range pubDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	debugf("Ignoring unparseable pubDate %q on '%s'\n", value, strings.TrimSpace(item.Title))
+	return time.Time{}, false
+}
+
+// discussLink returns the item's comment thread URL, preferring
+// <atom:link rel="replies"> over <wfw:commentRss>.
+func (item Item) discussLink() string {
+	for _, link := range item.AtomLinks {
+		if link.Rel == "replies" && strings.TrimSpace(link.Href) != "" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return strings.TrimSpace(item.CommentRSS)
+}
+
+// author returns the item's author from <dc:creator>, an Atom <author> name
+// or an RSS <author>, whose "email (Name)" form is reduced to the name.
+func (item Item) author() string {
+	for _, path := range []string{"creator", "author/name"} {
+		if value := lookupElement(item.Extra, path); value != "" {
+			return value
+		}
+	}
+	value := lookupElement(item.Extra, "author")
+	if open := strings.Index(value, "("); open >= 0 && strings.HasSuffix(value, ")") {
+		if name := strings.TrimSpace(value[open+1 : len(value)-1]); name != "" {
+			return name
+		}
+	}
+	return value
+}
+
+// SlackMessage structures the Block Kit API
+// See: https://api.slack.com/block-kit
+type SlackMessage struct {
+	Blocks      []SlackBlock      `json:"blocks"`                // A list of layout blocks
+	Attachments []SlackAttachment `json:"attachments,omitempty"` // Colored groups of blocks shown after Blocks
+	Text        string            `json:"text"`                  // Fallback text for notifications
+}
+
+// SlackAttachment is a legacy attachment, used to show a group of blocks
+// beside a colored bar.
+// See: https://api.slack.com/reference/messaging/attachments
+type SlackAttachment struct {
+	Color  string       `json:"color,omitempty"` // Hex color of the bar (e.g. "#36a64f")
+	Blocks []SlackBlock `json:"blocks"`          // Blocks shown inside the attachment
+}
+
+type SlackBlock struct {
+	Type      string          `json:"type"`                // Type of block (e.g., "header", "section", "divider")
+	Text      *SlackText      `json:"text,omitempty"`      // Text object, used by "header" and "section"
+	Accessory *SlackAccessory `json:"accessory,omitempty"` // Element shown alongside a "section"
+	Elements  []SlackElement  `json:"elements,omitempty"`  // Elements of a "context" block
+}
+
+// SlackElement is a text or image element of a context block.
+type SlackElement struct {
+	Type     string `json:"type"`                // "mrkdwn", "plain_text" or "image"
+	Text     string `json:"text,omitempty"`      // Content of a text element
+	ImageURL string `json:"image_url,omitempty"` // Source of an image element
+	AltText  string `json:"alt_text,omitempty"`  // Plain-text summary of an image element
+}
+
+// SlackAccessory is an element attached to a section block, such as an image.
+type SlackAccessory struct {
+	Type     string `json:"type"`      // Type of element (e.g., "image")
+	ImageURL string `json:"image_url"` // URL of the image to display
+	AltText  string `json:"alt_text"`  // Plain-text summary of the image
+}
+
+type SlackText struct {
+	Type  string `json:"type"`            // Type of text (e.g., "plain_text", "mrkdwn")
+	Text  string `json:"text"`            // The actual text content
+	Emoji bool   `json:"emoji,omitempty"` // Whether to render emojis (for plain_text)
+}
+
+// slackOptions controls how entries are rendered in the Slack message.
+type slackOptions struct {
+	ShowDiscussLink   bool              // Append a "discuss" link to entries that have one
+	Messages          messages          // Localized header and fallback text
+	Layout            string            // Block ordering, one of the layout* constants
+	ShowEnclosureSize bool              // Append the human-readable enclosure size to entries
+	HeaderDate        string            // Preformatted date appended to the header, if any
+	CategoryEmoji     map[string]string // Lowercased category → emoji used in place of the bullet
+	CategoryColors    map[string]string // Lowercased category → hex color; groups entries into colored attachments
+	ShowFavicon       bool              // Show the source favicon as an image accessory on entries
+	RichEntries       bool              // Follow each entry with a context block of its icon, author and date
+	PriorityMention   string            // Prepended when any entry is high priority (e.g. "<!here>")
+	NotifyOnEmpty     bool              // Post a heartbeat message when there are no entries
+	DryRun            bool              // Print payloads to stdout instead of posting them
+	Render            bool              // With DryRun, print a terminal preview of Slack messages instead of JSON
+	Location          *time.Location    // Time zone for entry dates; nil keeps the feed's own
+	PublishedFormat   string            // Go layout for entry dates, or publishedRelative (also used for "")
+	Now               time.Time         // Reference for relative entry dates; zero uses the current time
+	DescriptionLength int               // Characters of each description shown under its entry; 0 hides them
+	PostDelay         time.Duration     // Pause between consecutive posts of a multi-message digest
+
+	// Slack delivery modes beyond the incoming webhook. The zero values post a
+	// Block Kit message to the webhook.
+	BotToken          string             // Slack API token for scheduled and threaded delivery
+	Channel           string             // Channel posted to with BotToken
+	SendAt            string             // Schedule the digest for this time (HH:MM or RFC3339) with BotToken
+	Threaded          bool               // Post entries as replies under a parent message with BotToken
+	Canvas            bool               // Replace the content of the channel's canvas with the digest with BotToken
+	CanvasID          string             // Canvas to update under Canvas until Canvases records one
+	Canvases          map[string]string  // Channel → ID of its canvas, shared with the run's state
+	Format            string             // Webhook payload format, slackFormatBlocks or slackFormatWorkflow
+	WorkflowVariables []workflowVariable // Variables sent in the workflow format
+}
+
+// Slack message layouts selectable via SLACK_LAYOUT.
+const (
+	layoutDefault      = "default"       // Header, divider, entries
+	layoutSummaryFirst = "summary-first" // Header, summary count, divider, entries
+	layoutSummaryLast  = "summary-last"  // Header, divider, entries, divider, summary count
+	layoutEntriesOnly  = "entries-only"  // Entries only, no header or summary
+)
+
+// withDefaults fills in the English messages and the default layout when
+// opts leaves them unset, so a notifier's zero Options render a complete
+// digest.
+func (opts slackOptions) withDefaults() slackOptions {
+	if opts.Messages == (messages{}) {
+		opts.Messages = englishMessages
+	}
+	if opts.Layout == "" {
+		opts.Layout = layoutDefault
+	}
+	return opts
+}
+
+// headerText returns the localized digest header with the date suffix, if
+// one is configured.
+func (opts slackOptions) headerText() string {
+	if opts.HeaderDate != "" {
+		return opts.Messages.render(opts.Messages.Header, 0, "") + " — " + opts.HeaderDate
+	}
+	return opts.Messages.render(opts.Messages.Header, 0, "")
+}
+
+// parseLayout validates a SLACK_LAYOUT value, falling back to the default.
+func parseLayout(value string) string {
+	switch layout := strings.ToLower(strings.TrimSpace(value)); layout {
+	case "":
+		return layoutDefault
+	case layoutDefault, layoutSummaryFirst, layoutSummaryLast, layoutEntriesOnly:
+		return layout
+	default:
+		warnf("Warning: unknown SLACK_LAYOUT %q, using %s\n", value, layoutDefault)
+		return layoutDefault
+	}
+}
+
+// key identifies the entry for de-duplication and the seen state: its value
+// for field (see parseDedupField), by default its GUID where the feed
+// provides one, since links can pick up tracking parameters, and otherwise
+// its link normalized under level (see normalizeLink).
+func (entry FilteredEntry) key(field, level string) string {
+	if value := entry.dedupValue(field); value != "" {
+		return value
+	}
+	return normalizeLink(entry.Link, level)
+}
+
+// dedupeEntries drops entries sharing a key under field and level with an
+// earlier one, keeping the first occurrence.
+func dedupeEntries(entries []FilteredEntry, field, level string) []FilteredEntry {
+	seen := make(map[string]bool, len(entries))
+	var unique []FilteredEntry
+	for _, entry := range entries {
+		key := entry.key(field, level)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, entry)
+	}
+	return unique
+}
+
+// parseSortOrder validates a SORT_ORDER value, reporting whether entries
+// should be listed oldest first. The default is newest first.
+func parseSortOrder(value string) bool {
+	switch order := strings.ToLower(strings.TrimSpace(value)); order {
+	case "", "desc":
+		return false
+	case "asc":
+		return true
+	default:
+		warnf("Warning: unknown SORT_ORDER %q, using desc\n", value)
+		return false
+	}
+}
+
+// sortEntries orders entries by publication date, newest first unless
+// ascending is set. Undated entries go last, in their original order, unless
+// ranked by rankDateless, which makes the first the newest.
+func sortEntries(entries []FilteredEntry, ascending bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Published, entries[j].Published
+		switch {
+		case a.IsZero() && b.IsZero():
+			if ascending {
+				return entries[i].Rank > entries[j].Rank
+			}
+			return entries[i].Rank < entries[j].Rank
+		case a.IsZero() || b.IsZero():
+			return !a.IsZero() && b.IsZero()
+		case ascending:
+			return a.Before(b)
+		default:
+			return a.After(b)
+		}
+	})
+}
+
+// rankDateless ranks the entries of a feed by their position in it, 1 for
+// the first, when none of them is dated, under DATELESS_FEED_ORDER. Feeds
+// almost always list their newest items first, so the rank stands in for the
+// missing dates when sorting and for SINCE_LAST_RUN. A feed with any dated
+// entry is left alone, as there's no telling where its undated ones belong.
+func rankDateless(entries []FilteredEntry) {
+	if slices.ContainsFunc(entries, func(entry FilteredEntry) bool { return !entry.Published.IsZero() }) {
+		return
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+}
+
+// resolveLink makes a relative link absolute against base. Absolute links are
+// returned unchanged, as are links that fail to parse.
+func resolveLink(base *url.URL, link string) string {
+	ref, err := url.Parse(link)
+	if err != nil {
+		warnf("Warning: keeping unparseable link %q: %v\n", link, err)
+		return link
+	}
+	if ref.IsAbs() {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// fetchAndFilterRSSEntries fetches the RSS feed, parses it, and filters for
+// entries in the configured categories.
+func fetchAndFilterRSSEntries(ctx context.Context, client *http.Client, rssURL string, filters filterOptions) ([]FilteredEntry, error) {
+	body, finalURL, err := fetchFeed(ctx, client, rssURL)
+	if err != nil {
+		return nil, err
+	}
+	capture(ctx, "feed", "xml", body)
+
+	entries, parsed, err := filterFeed(body, filters)
+	if err != nil {
+		return nil, err
+	}
+	count(ctx, parsed)
+	// Feeds without a channel <link> or title are identified by the feed's
+	// own URL. Relative item links are resolved against the URL the feed was
+	// actually served from, after redirects; local feeds leave them as-is.
+	var favicon string
+	if finalURL != nil {
+		favicon = faviconURL(finalURL.String())
+	}
+	for i := range entries {
+		if finalURL != nil {
+			entries[i].Link = resolveLink(finalURL, entries[i].Link)
+			if entries[i].ImageURL != "" {
+				entries[i].ImageURL = resolveLink(finalURL, entries[i].ImageURL)
+			}
+		}
+		entries[i].FeedURL = rssURL
+		if entries[i].FaviconURL == "" {
+			entries[i].FaviconURL = favicon
+		}
+		if entries[i].Source == "" {
+			entries[i].Source = feedHost(rssURL)
+		}
+	}
+
+	if len(filters.AllowDomains) > 0 || len(filters.BlockDomains) > 0 {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if reason := filters.linkDomainReason(entry.Link); reason != "" {
+				debugf("Skipping entry due to %s: '%s' - %s\n", reason, entry.Title, entry.Link)
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		entries = kept
+	}
+	count(ctx, runStats{Filtered: len(entries)})
+
+	warnEmptyDedupField(entries, filters.DedupField, rssURL)
+	if unique := dedupeEntries(entries, filters.DedupField, filters.LinkNormalization); len(unique) != len(entries) {
+		infof("Removed %d duplicate entries from %s\n", len(entries)-len(unique), rssURL)
+		count(ctx, runStats{Duplicates: len(entries) - len(unique)})
+		entries = unique
+	}
+	return entries, nil
+}
+
+// defaultFetchConcurrency is how many feeds are fetched at once when
+// FETCH_CONCURRENCY is unset.
+const defaultFetchConcurrency = 4
+
+// feedResult is the outcome of fetching and filtering a single feed.
+type feedResult struct {
+	URL      string
+	Entries  []FilteredEntry
+	Elapsed  time.Duration
+	Attempts int // Requests made, counting retries
+	Err      error
+}
+
+// fetchFeeds fetches and filters feedURLs using up to concurrency workers,
+// each feed with its own settings from overrides, if any. Each worker writes
+// only its own feeds' slots, so the results come back in feedURLs order
+// however the fetches finish.
+func fetchFeeds(ctx context.Context, client *http.Client, feedURLs []string, filters filterOptions, concurrency int, overrides map[string]feedSettings) []feedResult {
+	results := make([]feedResult, len(feedURLs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(concurrency, 1), len(feedURLs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if settingsFrom(ctx).SoftDeadline.passed() {
+					results[i] = feedResult{URL: feedURLs[i], Err: errSoftDeadline}
+					continue
+				}
+				results[i] = fetchFeedWith(ctx, client, feedURLs[i], filters, overrides[feedURLs[i]])
+			}
+		}()
+	}
+	for i := range feedURLs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// errSoftDeadline is the error of a feed not fetched because the soft
+// deadline had passed.
+var errSoftDeadline = errors.New("not fetched: the soft deadline passed")
+
+// fetchFeedWith fetches and filters the feed at feedURL, bounding each
+// request by the feed's own timeout and retrying up to its own retry count
+// when override sets them.
+func fetchFeedWith(ctx context.Context, client *http.Client, feedURL string, filters filterOptions, override feedSettings) feedResult {
+	settings := settingsFrom(ctx)
+	if override.MaxRetries > 0 {
+		settings.MaxRetries = override.MaxRetries
+	}
+	if override.TimeoutSeconds > 0 {
+		bounded := *client
+		bounded.Timeout = time.Duration(override.TimeoutSeconds) * time.Second
+		client = &bounded
+	}
+	var attempts int
+	settings.Attempts = &attempts
+
+	start := time.Now()
+	entries, err := fetchAndFilterRSSEntries(withSettings(ctx, settings), client, feedURL, filters)
+	return feedResult{URL: feedURL, Entries: entries, Elapsed: time.Since(start), Attempts: attempts, Err: err}
+}
+
+// errTruncatedResponse indicates the feed body was cut short (e.g. the
+// connection dropped mid-download). It is transient, so the fetch is retried
+// rather than parsing incomplete XML.
+var errTruncatedResponse = errors.New("truncated response body")
+
+// defaultAcceptHeader nudges content-negotiating servers to return the feed
+// rather than an HTML page.
+const defaultAcceptHeader = "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8"
+
+// defaultFeedTimeout bounds each feed request, including reading the body,
+// when no timeout is configured.
+const defaultFeedTimeout = 30 * time.Second
+
+// feedAuthorization builds the Authorization header from RSS_BASIC_AUTH
+// ("user:pass") or RSS_BEARER_TOKEN, returning "" when neither is set.
+func feedAuthorization(basicAuth, bearerToken string) (string, error) {
+	switch {
+	case basicAuth != "" && bearerToken != "":
+		return "", fmt.Errorf("set only one of RSS_BASIC_AUTH and RSS_BEARER_TOKEN")
+	case basicAuth != "":
+		if !strings.Contains(basicAuth, ":") {
+			return "", fmt.Errorf("RSS_BASIC_AUTH must be in user:pass form")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(basicAuth)), nil
+	case bearerToken != "":
+		return "Bearer " + bearerToken, nil
+	}
+	return "", nil
+}
+
+// newFeedClient returns the HTTP client used to fetch feeds, bounding each
+// request by timeout, or defaultFeedTimeout when it's 0. A non-zero
+// minTLSVersion (e.g. tls.VersionTLS12) refuses connections negotiating an
+// older protocol version.
+func newFeedClient(minTLSVersion uint16, timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = defaultFeedTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	if minTLSVersion != 0 {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{MinVersion: minTLSVersion}
+		client.Transport = transport
+	}
+	return client
+}
+
+// parseTLSVersion converts an RSS_MIN_TLS_VERSION value such as "1.2" into a
+// crypto/tls version constant. An empty value returns 0 (the Go default).
+func parseTLSVersion(value string) (uint16, error) {
+	switch strings.TrimSpace(value) {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS version %q: expected 1.0, 1.1, 1.2 or 1.3", value)
+	}
+}
+
+// defaultUserAgent identifies the tool to feed providers, some of which block
+// Go's default "Go-http-client/1.1".
+const defaultUserAgent = "rss-notifications/1.0 (+https://github.com/Integralist/rss-notifications)"
+
+// userAgent returns the User-Agent sent on feed requests: the UserAgent set
+// in ctx (HTTP_USER_AGENT), or defaultUserAgent.
+func userAgent(ctx context.Context) string {
+	if ua := strings.TrimSpace(settingsFrom(ctx).UserAgent); ua != "" {
+		return ua
+	}
+	return defaultUserAgent
+}
+
+// feedStdin is read for the feed "-". Tests replace it.
+var feedStdin io.Reader = os.Stdin
+
+// localFeedPath reports whether feedURL names a local feed rather than one
+// to fetch: "-" for stdin, a file:// URL, or a path starting with "./",
+// "../" or "/". It returns the path to read.
+func localFeedPath(feedURL string) (string, bool) {
+	switch {
+	case feedURL == "-":
+		return feedURL, true
+	case strings.HasPrefix(feedURL, "file://"):
+		u, err := url.Parse(feedURL)
+		if err != nil {
+			return "", false
+		}
+		return u.Path, true
+	case strings.HasPrefix(feedURL, "./"), strings.HasPrefix(feedURL, "../"), strings.HasPrefix(feedURL, "/"):
+		return feedURL, true
+	}
+	return "", false
+}
+
+// readLocalFeed reads a feed from the local path, or from stdin for "-".
+func readLocalFeed(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(feedStdin)
+	}
+	return os.ReadFile(path)
+}
+
+// fetchFeed fetches the raw RSS feed body, along with the URL it was served
+// from once any redirects were followed. Local feeds are read from disk or
+// stdin instead and have no URL.
+func fetchFeed(ctx context.Context, client *http.Client, rssURL string) ([]byte, *url.URL, error) {
+	if path, ok := localFeedPath(rssURL); ok {
+		logFields(fmt.Sprintf("Reading RSS feed from: %s", rssURL), "feed_url", rssURL)
+		body, err := readLocalFeed(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading RSS feed: %w", err)
+		}
+		return body, nil, nil
+	}
+
+	logFields(fmt.Sprintf("Fetching RSS feed from: %s", rssURL), "feed_url", rssURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rssURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating RSS feed request: %w", err)
+	}
+	settings := settingsFrom(ctx)
+	accept := settings.AcceptHeader
+	if accept == "" {
+		accept = defaultAcceptHeader
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", userAgent(ctx))
+	// The HTTP client drops the Authorization header on redirects to another
+	// host.
+	if settings.FeedAuthorization != "" {
+		req.Header.Set("Authorization", settings.FeedAuthorization)
+	}
+	// Setting this ourselves turns off the transport's transparent
+	// decompression, so gzip bodies are decoded below.
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	// The body is read inside the retried call so a truncated download is
+	// retried like any other transient failure.
+	var body []byte
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return resp, nil
+		}
+		defer resp.Body.Close()
+
+		body, err = io.ReadAll(resp.Body)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errTruncatedResponse
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading RSS feed body: %w", err)
+		}
+		if resp.ContentLength >= 0 && int64(len(body)) != resp.ContentLength {
+			return nil, fmt.Errorf("got %d of %d bytes: %w", len(body), resp.ContentLength, errTruncatedResponse)
+		}
+		body, err = decodeContent(resp.Header.Get("Content-Encoding"), body)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errTruncatedResponse
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing RSS feed body: %w", err)
+		}
+		return resp, nil
+	})
+	if err != nil {
+		if isTLSVersionError(err) {
+			return nil, nil, fmt.Errorf("error fetching RSS feed: TLS version negotiation failed (check RSS_MIN_TLS_VERSION): %w", err)
+		}
+		return nil, nil, fmt.Errorf("error fetching RSS feed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("error fetching RSS feed: received status code %d", resp.StatusCode)
+	}
+
+	final := resp.Request.URL
+	if final.String() != rssURL && permanentlyMoved(resp) {
+		logFields(fmt.Sprintf("Feed %s has permanently moved to %s, consider updating RSS_FEED_URL", rssURL, final),
+			"feed_url", rssURL, "final_url", final.String())
+	}
+	return body, final, nil
+}
+
+// tlsAlertProtocolVersion is the TLS alert sent by a server that supports
+// none of the protocol versions the client offered.
+const tlsAlertProtocolVersion tls.AlertError = 70
+
+// isTLSVersionError reports whether err is a TLS handshake that failed
+// because client and server share no protocol version, e.g. a server capped
+// below RSS_MIN_TLS_VERSION.
+func isTLSVersionError(err error) bool {
+	var alertErr tls.AlertError
+	if errors.As(err, &alertErr) {
+		return alertErr == tlsAlertProtocolVersion
+	}
+	// Alerts received over TCP surface as a *net.OpError wrapping crypto/tls's
+	// unexported alert type, which like AlertError is the uint8 alert code.
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		code := reflect.ValueOf(opErr.Err)
+		return code.Kind() == reflect.Uint8 && code.Uint() == uint64(tlsAlertProtocolVersion)
+	}
+	return false
+}
+
+// permanentlyMoved reports whether every redirect leading to resp was
+// permanent (301 or 308), meaning the feed's configured URL is out of date.
+func permanentlyMoved(resp *http.Response) bool {
+	redirected := false
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		if r.StatusCode != http.StatusMovedPermanently && r.StatusCode != http.StatusPermanentRedirect {
+			return false
+		}
+		redirected = true
+	}
+	return redirected
+}
+
+// entityPattern matches semicolon-terminated HTML character references.
+var entityPattern = regexp.MustCompile(`&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+
+// unescapeEntities decodes HTML entities left in a link by feeds that
+// double-encode them (e.g. "?a=1&amp;b=2"). Only semicolon-terminated
+// references are decoded, so query parameters such as "&copy=1" survive, and
+// the single pass never double-decodes.
+func unescapeEntities(link string) string {
+	if !strings.Contains(link, "&") {
+		return link
+	}
+	return entityPattern.ReplaceAllStringFunc(link, html.UnescapeString)
+}
+
+// decodeContent undoes the response's Content-Encoding. Only gzip is
+// requested, so anything else is passed through untouched.
+func decodeContent(encoding string, body []byte) ([]byte, error) {
+	if !strings.EqualFold(strings.TrimSpace(encoding), "gzip") {
+		return body, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// errDoctypeRejected is returned for feeds declaring a DOCTYPE when
+// RSS_REJECT_DOCTYPE is enabled.
+var errDoctypeRejected = errors.New("feed contains a DOCTYPE declaration")
+
+// rejectDoctype returns errDoctypeRejected if the document declares a
+// <!DOCTYPE> before its root element.
+//
+// encoding/xml never fetches external entities or expands entities declared
+// in a DTD (unknown entities are a parse error in strict mode), so XXE and
+// entity-expansion attacks don't apply. Legitimate feeds don't need a
+// DOCTYPE, so rejecting them outright is a belt-and-braces guard.
+func rejectDoctype(body []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil // Leave reporting malformed XML to the parser proper
+		}
+		switch t := tok.(type) {
+		case xml.Directive:
+			if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(string(t))), "DOCTYPE") {
+				return errDoctypeRejected
+			}
+		case xml.StartElement:
+			return nil
+		}
+	}
+}
+
+// filterRSSEntries parses the RSS or Atom feed body and filters for entries
+// in the configured categories.
+func filterRSSEntries(body []byte, filters filterOptions) ([]FilteredEntry, error) {
+	entries, _, err := filterFeed(body, filters)
+	return entries, err
+}
+
+// filterFeed is filterRSSEntries, also returning the parsed item counts for
+// the run's SUMMARY line.
+func filterFeed(body []byte, filters filterOptions) ([]FilteredEntry, runStats, error) {
+	var filteredEntries []FilteredEntry
+
+	if filters.RejectDoctype {
+		if err := rejectDoctype(body); err != nil {
+			return nil, runStats{}, err
+		}
+	}
+
+	items, meta, err := parseFeed(body)
+	if err != nil {
+		errorf("XML unmarshal error. This might be due to encoding or complex CDATA. Error: %v", err)
+		return nil, runStats{}, err
+	}
+
+	parsed := runStats{Fetched: len(items), Skipped: meta.Skipped}
+	if meta.Skipped > 0 {
+		warnf("Warning: skipped %d malformed item(s) in feed\n", meta.Skipped)
+	}
+
+	favicon := faviconURL(meta.Link)
+
+	for _, item := range items {
+		matchedCategory, isDNSEntry := filters.matchCategory(item)
+		matchedBy := fmt.Sprintf("category %q", matchedCategory)
+		if !isDNSEntry {
+			var keyword string
+			if keyword, isDNSEntry = filters.matchKeyword(item); isDNSEntry {
+				matchedBy = fmt.Sprintf("keyword %q", keyword)
+			}
+		}
+
+		link := strings.TrimSpace(item.Link)
+		if link == "" {
+			// Some feeds omit <link> but carry the permalink in <guid>.
+			link = item.GUID.permalink()
+		}
+		link = unescapeEntities(link)
+		enclosure := item.primaryEnclosure()
+
+		published, _ := item.published()
+		forced := filters.isForceIncluded(item, link)
+		if !forced {
+			if !isDNSEntry {
+				continue
+			}
+			if reason := filters.skipReason(item, enclosure, published); reason != "" {
+				debugf("Skipping DNS entry due to %s: '%s'\n", reason, strings.TrimSpace(item.Title))
+				continue
+			}
+		}
+
+		if link != "" {
+			entryTitle := strings.TrimSpace(item.Title)
+			if entryTitle == "" {
+				entryTitle = "Untitled Article"
+			}
+			filteredEntries = append(filteredEntries, FilteredEntry{
+				Title:       entryTitle,
+				Link:        link,
+				GUID:        strings.TrimSpace(item.GUID.Value),
+				ID:          item.dedupID(filters.DedupField),
+				Category:    matchedCategory,
+				DiscussLink: item.discussLink(),
+				Enclosure:   enclosure,
+				FaviconURL:  favicon,
+				ImageURL:    item.imageURL(),
+				Priority:    filters.isPriority(item),
+				Source:      meta.Title,
+				Author:      item.author(),
+				Published:   published,
+				Description: item.summary(),
+			})
+			if forced {
+				infof("Force-included entry: '%s' - %s\n", entryTitle, link)
+			} else {
+				debugf("Found DNS entry by %s: '%s' - %s\n", matchedBy, entryTitle, link)
+			}
+		}
+	}
+	return filteredEntries, parsed, nil
+}
+
+// defaultDescriptionLength is how much of each description is shown when
+// DESCRIPTION_MAX_LENGTH is unset.
+const defaultDescriptionLength = 200
+
+// mrkdwnEscaper escapes the characters Slack reserves for its control
+// sequences, such as <url|text> links.
+// See: https://api.slack.com/reference/surfaces/formatting#escaping
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeMrkdwn makes feed-supplied text safe to interpolate into Slack
+// mrkdwn.
+func escapeMrkdwn(s string) string {
+	return mrkdwnEscaper.Replace(s)
+}
+
+// formatEntryLine renders a single entry as a Slack mrkdwn line.
+func formatEntryLine(entry FilteredEntry, opts slackOptions) string {
+	bullet := "•"
+	if marker := entry.changeMarker(); marker != "" {
+		bullet = strings.TrimSpace(marker)
+	}
+	if emoji, ok := opts.CategoryEmoji[strings.ToLower(entry.Category)]; ok {
+		bullet = emoji
+	}
+	line := fmt.Sprintf("%s <%s|%s>", bullet, entry.Link, escapeMrkdwn(entry.Title))
+	if !entry.Published.IsZero() && !opts.RichEntries { // Rich entries show it in their context block
+		line += " — " + opts.formatPublished(entry.Published)
+	}
+	if opts.ShowDiscussLink && entry.DiscussLink != "" {
+		line += fmt.Sprintf(" (<%s|discuss>)", entry.DiscussLink)
+	}
+	if opts.ShowEnclosureSize && entry.Enclosure != nil {
+		if n, ok := entry.Enclosure.size(); ok {
+			line += fmt.Sprintf(" (%s)", formatBytes(n))
+		}
+	}
+	if entry.Enclosure != nil {
+		line += "\n" + enclosureLine(*entry.Enclosure)
+	}
+	if opts.DescriptionLength > 0 && entry.Description != "" {
+		line += "\n" + escapeMrkdwn(truncateRunes(entry.Description, opts.DescriptionLength))
+	}
+	return line
+}
+
+// enclosureLine renders a link to enc, labelled by its media type.
+func enclosureLine(enc Enclosure) string {
+	icon, label := "📎", "Download"
+	switch mediaType, _, _ := strings.Cut(strings.ToLower(enc.Type), "/"); mediaType {
+	case "audio":
+		icon, label = "🎧", "Listen"
+	case "video":
+		icon, label = "🎬", "Watch"
+	}
+	if enc.Type != "" {
+		label += " (" + enc.Type + ")"
+	}
+	return fmt.Sprintf("%s <%s|%s>", icon, enc.URL, label)
+}
+
+// buildSlackMessage constructs the Block Kit message for entries, arranging
+// the header, summary and entry blocks according to opts.Layout.
+func buildSlackMessage(entries []FilteredEntry, opts slackOptions) SlackMessage {
+	headerText := opts.headerText()
+	header := SlackBlock{
+		Type: "header",
+		Text: &SlackText{Type: "plain_text", Text: headerText, Emoji: true},
+	}
+	divider := SlackBlock{Type: "divider"}
+	summary := SlackBlock{
+		Type: "section",
+		Text: &SlackText{Type: "mrkdwn", Text: opts.Messages.render(opts.Messages.Summary, len(entries), "")},
+	}
+
+	// Label each feed's entries when the digest combines several feeds.
+	labelSources := hasMultipleFeeds(entries)
+
+	// With category colors configured the entries move into colored
+	// attachments, which Slack renders after the message blocks.
+	var entryBlocks []SlackBlock
+	var attachments []SlackAttachment
+	if len(opts.CategoryColors) > 0 {
+		attachments = buildCategoryAttachments(entries, opts, labelSources)
+	} else {
+		entryBlocks = buildEntryBlocks(entries, opts, labelSources)
+	}
+
+	// Construct Slack message using Block Kit
+	var blocks []SlackBlock
+	mention := priorityMention(entries, opts)
+	if mention != "" {
+		blocks = append(blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: mention},
+		})
+	}
+	switch opts.Layout {
+	case layoutSummaryFirst:
+		blocks = append(blocks, header, summary, divider)
+		blocks = append(blocks, entryBlocks...)
+	case layoutSummaryLast:
+		blocks = append(blocks, header, divider)
+		blocks = append(blocks, entryBlocks...)
+		blocks = append(blocks, divider, summary)
+	case layoutEntriesOnly:
+		blocks = append(blocks, entryBlocks...)
+	default:
+		blocks = append(blocks, header, divider)
+		blocks = append(blocks, entryBlocks...)
+	}
+
+	// Fallback text for notifications that don't support Block Kit
+	first := fmt.Sprintf("<%s|%s>", entries[0].Link, escapeMrkdwn(entries[0].Title))
+	fallbackText := opts.Messages.render(opts.Messages.Fallback, len(entries), first)
+	if mention != "" {
+		fallbackText = mention + " " + fallbackText
+	}
+
+	return SlackMessage{
+		Blocks:      blocks,
+		Attachments: attachments,
+		Text:        fallbackText,
+	}
+}
+
+// buildEntryBlocks renders a section block per entry, preceded by a source
+// label whenever the feed changes if labelSources is set. A DIGEST_MODE=diff
+// digest is divided into new and gone sections.
+func buildEntryBlocks(entries []FilteredEntry, opts slackOptions, labelSources bool) []SlackBlock {
+	var blocks []SlackBlock
+	for i, entry := range entries {
+		changed := i == 0 || entries[i-1].Change != entry.Change
+		if heading := opts.changeHeading(entry.Change); heading != "" && changed {
+			blocks = append(blocks, SlackBlock{
+				Type: "section",
+				Text: &SlackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*", escapeMrkdwn(heading))},
+			})
+		}
+		if labelSources && (changed || entries[i-1].FeedURL != entry.FeedURL) {
+			blocks = append(blocks, SlackBlock{
+				Type: "section",
+				Text: &SlackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*", escapeMrkdwn(entry.Source))},
+			})
+		}
+		blocks = append(blocks, entryBlocks(entry, opts)...)
+	}
+	return blocks
+}
+
+// changeHeading returns the heading of the DIGEST_MODE=diff section holding
+// entries with change, or "" outside a diff digest.
+func (opts slackOptions) changeHeading(change string) string {
+	switch change {
+	case changeNew:
+		return opts.Messages.DiffNew
+	case changeGone:
+		return opts.Messages.DiffGone
+	}
+	return ""
+}
+
+// entryBlocks renders entry as a section block, followed with
+// SLACK_RICH_ENTRIES by a context block of its source icon, author and date
+// in muted text. The context block is left out when there is nothing to
+// show, and splitSlackMessage keeps it in the same message as its section.
+func entryBlocks(entry FilteredEntry, opts slackOptions) []SlackBlock {
+	blocks := []SlackBlock{{
+		Type:      "section",
+		Text:      &SlackText{Type: "mrkdwn", Text: formatEntryLine(entry, opts)},
+		Accessory: entryAccessory(entry, opts),
+	}}
+	if !opts.RichEntries {
+		return blocks
+	}
+
+	var elements []SlackElement
+	if emoji, ok := opts.CategoryEmoji[strings.ToLower(entry.Category)]; ok {
+		elements = append(elements, SlackElement{Type: "mrkdwn", Text: emoji})
+	} else if opts.ShowFavicon && entry.FaviconURL != "" {
+		elements = append(elements, SlackElement{Type: "image", ImageURL: entry.FaviconURL, AltText: "source icon"})
+	}
+	var details []string
+	for _, detail := range []string{entry.Author, entry.Source} {
+		if detail != "" {
+			details = append(details, escapeMrkdwn(detail))
+		}
+	}
+	if !entry.Published.IsZero() {
+		details = append(details, opts.formatPublished(entry.Published))
+	}
+	if len(details) > 0 {
+		elements = append(elements, SlackElement{Type: "mrkdwn", Text: strings.Join(details, " · ")})
+	}
+	if len(elements) > 0 {
+		blocks = append(blocks, SlackBlock{Type: "context", Elements: elements})
+	}
+	return blocks
+}
+
+// entryAccessory returns the image shown beside an entry's section: the
+// article image when the feed has one, otherwise the source favicon if
+// SLACK_SHOW_FAVICON is set, otherwise nil.
+func entryAccessory(entry FilteredEntry, opts slackOptions) *SlackAccessory {
+	switch {
+	case entry.ImageURL != "":
+		return &SlackAccessory{Type: "image", ImageURL: entry.ImageURL, AltText: entry.Title}
+	case opts.ShowFavicon && entry.FaviconURL != "" && !opts.RichEntries: // Rich entries show it in their context block
+		return &SlackAccessory{Type: "image", ImageURL: entry.FaviconURL, AltText: "source icon"}
+	}
+	return nil
+}
+
+// buildCategoryAttachments groups entries by their matched category, in order
+// of first appearance, and renders each group as an attachment headed by the
+// category name. Groups without a configured color get an uncolored bar.
+func buildCategoryAttachments(entries []FilteredEntry, opts slackOptions, labelSources bool) []SlackAttachment {
+	var order []string
+	groups := make(map[string][]FilteredEntry)
+	for _, entry := range entries {
+		key := strings.ToLower(entry.Category)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], entry)
+	}
+
+	attachments := make([]SlackAttachment, 0, len(order))
+	for _, key := range order {
+		group := groups[key]
+		var blocks []SlackBlock
+		if name := group[0].Category; name != "" {
+			blocks = append(blocks, SlackBlock{
+				Type:     "context",
+				Elements: []SlackElement{{Type: "mrkdwn", Text: fmt.Sprintf("*%s*", escapeMrkdwn(name))}},
+			})
+		}
+		blocks = append(blocks, buildEntryBlocks(group, opts, labelSources)...)
+		attachments = append(attachments, SlackAttachment{Color: opts.CategoryColors[key], Blocks: blocks})
+	}
+	return attachments
+}
+
+// buildEmptySlackMessage constructs the heartbeat message sent when there are
+// no entries and NOTIFY_ON_EMPTY is enabled.
+func buildEmptySlackMessage(opts slackOptions) SlackMessage {
+	headerText := opts.headerText()
+	return SlackMessage{
+		Blocks: []SlackBlock{
+			{
+				Type: "header",
+				Text: &SlackText{Type: "plain_text", Text: headerText, Emoji: true},
+			},
+			{Type: "divider"},
+			{
+				Type: "section",
+				Text: &SlackText{Type: "mrkdwn", Text: opts.Messages.Empty},
+			},
+		},
+		Text: opts.Messages.Empty,
+	}
+}
+
+// hasMultipleFeeds reports whether entries came from more than one feed.
+func hasMultipleFeeds(entries []FilteredEntry) bool {
+	if len(entries) < 2 {
+		return false
+	}
+	for _, entry := range entries[1:] {
+		if entry.FeedURL != entries[0].FeedURL {
+			return true
+		}
+	}
+	return false
+}
+
+// priorityMention returns the configured mention when any entry is high
+// priority, otherwise "" so routine digests stay silent.
+func priorityMention(entries []FilteredEntry, opts slackOptions) string {
+	if opts.PriorityMention == "" {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.Priority {
+			return opts.PriorityMention
+		}
+	}
+	return ""
+}
+
+// maxSlackBlocks is the most blocks Slack accepts in a single message.
+const maxSlackBlocks = 50
+
+// splitSlackMessage breaks msg into messages of at most maxSlackBlocks blocks.
+// The header and summary blocks stay wherever the layout placed them, so only
+// the first message carries the header and overflow messages start directly
+// with entry sections.
+func splitSlackMessage(msg SlackMessage) []SlackMessage {
+	if len(msg.Attachments) > 0 {
+		return splitSlackAttachments(msg)
+	}
+	if len(msg.Blocks) <= maxSlackBlocks {
+		return []SlackMessage{msg}
+	}
+
+	var chunks []SlackMessage
+	for start := 0; start < len(msg.Blocks); {
+		end := start + keepEntryPairs(msg.Blocks[start:], maxSlackBlocks)
+		chunks = append(chunks, SlackMessage{Blocks: msg.Blocks[start:end], Text: msg.Text})
+		start = end
+	}
+	return chunks
+}
+
+// keepEntryPairs returns how many of blocks, at most n, fit in a message
+// without separating an entry's section from the context block after it.
+func keepEntryPairs(blocks []SlackBlock, n int) int {
+	if n >= len(blocks) {
+		return len(blocks)
+	}
+	if n > 0 && blocks[n].Type == "context" && blocks[n-1].Type == "section" {
+		return n - 1
+	}
+	return n
+}
+
+// splitSlackAttachments is splitSlackMessage for messages whose entries are in
+// attachments. The first message keeps the top-level blocks, and attachments
+// that straddle a message boundary continue with the same color in the next.
+func splitSlackAttachments(msg SlackMessage) []SlackMessage {
+	chunk := SlackMessage{Blocks: msg.Blocks, Text: msg.Text}
+	used := len(msg.Blocks)
+	var chunks []SlackMessage
+	for _, attachment := range msg.Attachments {
+		blocks := attachment.Blocks
+		for len(blocks) > 0 {
+			if used == maxSlackBlocks {
+				chunks = append(chunks, chunk)
+				chunk, used = SlackMessage{Text: msg.Text}, 0
+			}
+			n := keepEntryPairs(blocks, maxSlackBlocks-used)
+			if n == 0 { // Only an entry's section would fit
+				chunks = append(chunks, chunk)
+				chunk, used = SlackMessage{Text: msg.Text}, 0
+				continue
+			}
+			chunk.Attachments = append(chunk.Attachments, SlackAttachment{Color: attachment.Color, Blocks: blocks[:n]})
+			blocks, used = blocks[n:], used+n
+		}
+	}
+	return append(chunks, chunk)
+}
+
+// sendNotificationToSlack sends the list of filtered entries to the Slack webhook.
+func sendNotificationToSlack(ctx context.Context, webhookURL string, entries []FilteredEntry, opts slackOptions) error {
+	post := postSlackMessage
+	if opts.DryRun {
+		post = printPayload
+		if opts.Render {
+			post = renderPayload
+		}
+	} else if webhookURL == "" {
+		errorf("Error: SLACK_WEBHOOK_URL is not set. Cannot send Slack notification.\n")
+		return fmt.Errorf("SLACK_WEBHOOK_URL is not configured")
+	}
+
+	if len(entries) == 0 {
+		if !opts.NotifyOnEmpty {
+			infof("No new DNS-related entries found to send to Slack.\n")
+			return nil
+		}
+		infof("Sending empty digest heartbeat to Slack...\n")
+		if _, err := post(ctx, webhookURL, buildEmptySlackMessage(opts)); err != nil {
+			return err
+		}
+		infof("Successfully sent heartbeat to Slack.\n")
+		return nil
+	}
+
+	chunks := splitSlackMessage(buildSlackMessage(entries, opts))
+
+	logFields(fmt.Sprintf("Sending %d DNS entries to Slack in %d message(s)...", len(entries), len(chunks)),
+		"notifier", "slack", "entry_count", len(entries), "message_count", len(chunks))
+
+	var errs []error
+	sent := 0
+	for i, chunk := range chunks {
+		if i > 0 && !opts.DryRun {
+			if err := pause(ctx, opts.PostDelay); err != nil {
+				errs = append(errs, fmt.Errorf("message %d of %d: %w", i+1, len(chunks), err))
+				break
+			}
+		}
+		responseBody, err := post(ctx, webhookURL, chunk)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("message %d of %d: %w", i+1, len(chunks), err))
+			continue
+		}
+		sent++
+		if strings.TrimSpace(responseBody) != "ok" {
+			infof("Slack API response: %s\n", responseBody)
+		}
+	}
+
+	if opts.DryRun {
+		infof("Dry run: would have sent %d entries in %d message(s) to Slack.\n", len(entries), sent)
+	} else {
+		infof("Sent %d of %d message(s) to Slack.\n", sent, len(chunks))
+	}
+	return errors.Join(errs...)
+}
+
+// webhookClient posts notifications to chat webhooks and the Slack API. Tests
+// swap it for an httptest server's client.
+var webhookClient = &http.Client{Timeout: 15 * time.Second}
+
+// postWebhook POSTs body to a webhook using webhookClientFor(ctx), aborting
+// if ctx is done first.
+func postWebhook(ctx context.Context, webhookURL, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return webhookClientFor(ctx).Do(req)
+}
+
+// postSlackMessage POSTs a single JSON payload to the Slack webhook, returning
+// the response body on success.
+func postSlackMessage(ctx context.Context, webhookURL string, slackPayload any) (string, error) {
+	// Marshal the Slack payload struct into JSON
+	payloadBytes, err := json.Marshal(slackPayload)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling Slack payload to JSON: %w", err)
+	}
+	capture(ctx, "slack-payload", "json", payloadBytes)
+
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		return postWebhook(ctx, webhookURL, "application/json", payloadBytes)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error sending message to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("error from Slack API with status %d: %s", resp.StatusCode, string(responseBody))
+	}
+
+	return string(responseBody), nil
+}
+
+// selfTestEntry is the fixed entry -self-test delivers.
+var selfTestEntry = FilteredEntry{
+	Title: "🔧 rss-notifications self-test",
+	Link:  "https://github.com/integralist/rss-notifications",
+}
+
+// runSelfTest sends selfTestEntry through every notifier a run would use, to
+// verify the configuration end-to-end without touching any feed. A scheduled
+// Slack digest is posted immediately instead, so the result is seen now.
+func runSelfTest(ctx context.Context, rc runConfig, report *runReport) error {
+	opts := rc.Slack
+	opts.SendAt = ""
+	notifiers, err := newNotifiers(rc.Config, opts, report)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, notifier := range notifiers {
+		infof("Sending self-test message via %T...\n", notifier)
+		if err := notifier.Send(ctx, []FilteredEntry{selfTestEntry}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	infof("Self-test succeeded for %d notifier(s).\n", len(notifiers))
+	return nil
+}
+
+// formatBytes renders a byte count using decimal units, e.g. "42 MB".
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	suffixes := []string{"KB", "MB", "GB", "TB"}
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f %s", value, suffixes[i])
+	}
+	return fmt.Sprintf("%.0f %s", value, suffixes[i])
+}
+
+// displayLocation returns the time zone named by DISPLAY_TIMEZONE (e.g.
+// "Europe/London") for rendering dates, defaulting to the local time zone.
+func displayLocation(name string) *time.Location {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		warnf("Warning: invalid DISPLAY_TIMEZONE %q, using local time: %v\n", name, err)
+		return time.Local
+	}
+	return loc
+}
+
+// parseMapping parses a comma-separated list of key=value pairs, e.g.
+// "dns=🌐,security=🔒". Keys are normalized with normalizeKey when non-nil.
+// Malformed pairs are skipped with a warning.
+func parseMapping(value string, normalizeKey func(string) string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range splitList(value) {
+		key, val, found := strings.Cut(pair, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !found || key == "" || val == "" {
+			warnf("Warning: skipping malformed mapping %q, expected key=value\n", pair)
+			continue
+		}
+		if normalizeKey != nil {
+			key = normalizeKey(key)
+		}
+		mapping[key] = val
+	}
+	return mapping
+}
+
+// parseColors parses CATEGORY_COLORS, a mapping of category to hex color like
+// "dns=#36a64f,security=e01e5a". The "#" is optional; values that aren't
+// 3- or 6-digit hex colors are skipped with a warning.
+func parseColors(value string) map[string]string {
+	colors := parseMapping(value, strings.ToLower)
+	for category, color := range colors {
+		hex := strings.TrimPrefix(color, "#")
+		if _, err := strconv.ParseUint(hex, 16, 32); err != nil || (len(hex) != 3 && len(hex) != 6) {
+			warnf("Warning: skipping invalid color %q for category %q\n", color, category)
+			delete(colors, category)
+			continue
+		}
+		colors[category] = "#" + strings.ToLower(hex)
+	}
+	return colors
+}
+
+// Main runs the rss-notifications command: it reads flags, the config file
+// and the environment, then fetches the feeds and delivers the digest,
+// exiting with status 1 on failure.
+func Main() {
+	discoverURL := flag.String("discover", "", "discover feeds declared by the given site URL and print them")
+	discoverAndRun := flag.Bool("discover-and-run", false, "with -discover, use the first discovered feed instead of RSS_FEED_URL")
+	benchmark := flag.Int("benchmark", 0, "parse the feed N times and report timings instead of notifying")
+	benchmarkFile := flag.String("benchmark-file", "", "with -benchmark, read the feed from this local file instead of RSS_FEED_URL")
+	selfTest := flag.Bool("self-test", false, "post a test message through the configured notifiers and exit without fetching any feed")
+	release := flag.Bool("release-pending", false, "send the entries a GUARD_AGAINST_FLOOD run held in PENDING_FILE and exit without fetching any feed")
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "read settings from this JSON config file; environment variables override it")
+	verbose := flag.Bool("verbose", false, "log at debug level, including every matched entry; overrides LOG_LEVEL")
+	quiet := flag.Bool("quiet", false, "log only warnings and errors; overrides LOG_LEVEL")
+	render := flag.Bool("render", false, "with DRY_RUN, print Slack messages as a terminal preview instead of JSON")
+	skipSlackHostCheck := flag.Bool("skip-slack-host-check", false, "accept a SLACK_WEBHOOK_URL on a host other than hooks.slack.com, e.g. behind a proxy")
+	flag.Parse()
+
+	// The config file is loaded first as its env entries may set LOG_FORMAT
+	// and LOG_LEVEL.
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("Critical Error: %v\n", err)
+	}
+	cfg.Render = *render
+	level := parseLogLevel(cfg.getenv("LOG_LEVEL"))
+	switch {
+	case *verbose:
+		level = slog.LevelDebug
+	case *quiet:
+		level = slog.LevelWarn
+	}
+	if err := setupLogging(cfg.getenv("LOG_FORMAT"), level); err != nil {
+		log.Fatalf("Critical Error: %v\n", err)
+	}
+	rc, err := cfg.resolve()
+	if err != nil {
+		fatalf("Critical Error: %v\n", err)
+	}
+
+	if cfg.SlackWebhookURL != "" {
+		host := slackWebhookHost
+		if *skipSlackHostCheck || cfg.envBool("SKIP_SLACK_HOST_CHECK", false) {
+			host = ""
+		}
+		if err := validateWebhookURL("SLACK_WEBHOOK_URL", cfg.SlackWebhookURL, host); err != nil {
+			fatalf("Critical Error: %v\n", err)
+		}
+	}
+	if cfg.GoogleChatWebhookURL != "" {
+		if err := validateWebhookURL("GOOGLE_CHAT_WEBHOOK_URL", cfg.GoogleChatWebhookURL, ""); err != nil {
+			fatalf("Critical Error: %v\n", err)
+		}
+	}
+
+	// The HTTP settings travel in the context, so the notifiers and feed
+	// fetches see them without any package state.
+	counter := &runCounter{}
+	httpSettings := rc.HTTP
+	httpSettings.Counter = counter
+	if rc.SoftDeadline > 0 {
+		httpSettings.SoftDeadline = newSoftDeadline(time.Now().Add(rc.SoftDeadline))
+	}
+	ctx := withSettings(context.Background(), httpSettings)
+
+	// RUN_TIMEOUT_SECONDS bounds the whole run, on top of the per-request
+	// timeouts.
+	if rc.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rc.RunTimeout)
+		defer cancel()
+	}
+
+	if *selfTest {
+		if err := runSelfTest(ctx, rc, newRunReport(time.Now())); err != nil {
+			fatalf("Self-test failed: %v\n", err)
+		}
+		return
+	}
+	if *release {
+		if err := releasePending(ctx, rc, newRunReport(time.Now())); err != nil {
+			fatalf("Error releasing held entries: %v\n", err)
+		}
+		return
+	}
+
+	feedClient := newFeedClient(rc.MinTLSVersion, time.Duration(rc.TimeoutSeconds)*time.Second)
+
+	if *discoverURL != "" {
+		feeds, err := discoverFeeds(ctx, feedClient, *discoverURL)
+		if err != nil {
+			fatalf("Error during feed discovery: %v\n", err)
+		}
+		if len(feeds) == 0 {
+			fatalf("No feeds declared by %s\n", *discoverURL)
+		}
+		if !*discoverAndRun {
+			for _, feed := range feeds {
+				fmt.Println(feed)
+			}
+			return
+		}
+		infof("Discovered %d feed(s), using: %s\n", len(feeds), feeds[0])
+		rc.FeedURLs = feeds[:1]
+	} else if *discoverAndRun {
+		fatalf("Critical Error: -discover-and-run requires -discover. Exiting.")
+	}
+
+	if *benchmark > 0 {
+		var body []byte
+		if *benchmarkFile != "" {
+			body, err = os.ReadFile(*benchmarkFile)
+		} else if len(rc.FeedURLs) > 0 {
+			body, _, err = fetchFeed(ctx, feedClient, rc.FeedURLs[0])
+		} else {
+			fatalf("Critical Error: -benchmark requires -benchmark-file or RSS_FEED_URL. Exiting.")
+		}
+		if err != nil {
+			fatalf("Error loading feed for benchmark: %v\n", err)
+		}
+		if err := runBenchmark(body, *benchmark, rc.Filters); err != nil {
+			fatalf("Error during benchmark: %v\n", err)
+		}
+		return
+	}
+
+	infof("Starting Go script: Fetch and filter DNS news...\n")
+
+	start := time.Now()
+	report := newRunReport(start)
+	err = run(ctx, feedClient, rc, report)
+	if rc.ReportFile != "" {
+		report.finish(time.Now(), err)
+		if werr := report.write(rc.ReportFile); werr != nil {
+			warnf("Warning: failed to write run report: %v\n", werr)
+			err = errors.Join(err, fmt.Errorf("error writing run report: %w", werr))
+		}
+	}
+	infof("%s\n", counter.snapshot().summary(time.Since(start)))
+	if err != nil {
+		fatalf("Error: %v\n", err)
+	}
+	infof("Go script finished successfully.\n")
+}
+
+// errNoFeeds is returned when RSS_FEED_URL and the config file leave no feed
+// to fetch, e.g. RSS_FEED_URL is a list of only commas, so a misconfigured
+// deployment fails loudly rather than exiting quietly.
+var errNoFeeds = errors.New("no feed URLs configured: set RSS_FEED_URL or the config file's feeds")
+
+// run fetches and filters the feeds then delivers a combined digest,
+// recording what happened in report. Failures that don't stop the run, like
+// one of several feeds failing, are still returned once it completes.
+func run(ctx context.Context, feedClient *http.Client, rc runConfig, report *runReport) (err error) {
+	feedURLs, filters, opts := rc.FeedURLs, rc.Filters, rc.Slack
+	if len(feedURLs) == 0 {
+		return errNoFeeds
+	}
+	if notifierName(rc.Notifier) == "slack" && rc.SlackWebhookURL == "" && opts.BotToken == "" &&
+		rc.GoogleChatWebhookURL == "" && rc.GenericWebhookURL == "" && !opts.DryRun {
+		warnf("Warning: SLACK_WEBHOOK_URL environment variable not set. Slack notification will fail.\n")
+	}
+
+	// A failing feed is logged and skipped so the others still get notified.
+	// Entries are sorted within each feed so multi-feed digests stay grouped
+	// by feed.
+	var filteredEntries []FilteredEntry
+	var feedErrs []error
+	for _, result := range fetchFeeds(ctx, feedClient, feedURLs, filters, rc.Concurrency, rc.FeedSettings) {
+		report.recordFeed(result)
+		if errors.Is(result.Err, errSoftDeadline) {
+			// Not a failure of the feed, so the run still delivers what's ready.
+			logFields(fmt.Sprintf("Skipping %s: %v", result.URL, result.Err), "feed_url", result.URL)
+			continue
+		}
+		if err := result.Err; err != nil {
+			logFields(fmt.Sprintf("Error during RSS fetching/filtering of %s: %v", result.URL, err), "feed_url", result.URL, "error", err.Error())
+			feedErrs = append(feedErrs, fmt.Errorf("%s: %w", result.URL, err))
+			continue
+		}
+		if rc.DatelessOrder {
+			rankDateless(result.Entries)
+		}
+		sortEntries(result.Entries, rc.Ascending)
+		filteredEntries = append(filteredEntries, result.Entries...)
+	}
+	if len(feedErrs) == len(feedURLs) {
+		return fmt.Errorf("error during RSS fetching/filtering: %w", errors.Join(feedErrs...))
+	}
+
+	var partialErrs []error
+	if len(feedErrs) > 0 {
+		partialErrs = append(partialErrs, fmt.Errorf("error during RSS fetching/filtering: %w", errors.Join(feedErrs...)))
+	}
+	defer func() {
+		err = errors.Join(append([]error{err}, partialErrs...)...)
+	}()
+
+	stateFile := rc.StateFile
+	state, err := loadState(stateFile, feedURLs)
+	if err != nil {
+		return err
+	}
+	if rc.DedupWindow > 0 {
+		// MAX_AGE_DAYS is capped at the window, so these entries can't come back.
+		if pruned := state.pruneBefore(report.StartedAt.Add(-rc.DedupWindow)); pruned > 0 {
+			logFields(fmt.Sprintf("Forgetting %d seen entries published over %s ago.", pruned, rc.DedupWindow), "entry_count", pruned)
+		}
+	}
+	seen, lastRun := state.Seen, state.LastRun
+	opts.Canvases = state.Canvases // SLACK_CANVAS records the canvas it creates
+	canvases := maps.Clone(state.Canvases)
+	fetched := filteredEntries
+	seenKey := func(entry FilteredEntry) string {
+		return entry.seenKey(rc.SeenKey, filters.DedupField, filters.LinkNormalization)
+	}
+	diff, daily := rc.DigestMode == digestDiff, rc.DigestMode == digestDailyFresh
+
+	// Under DEDUP_SCOPE=sink each notifier has seen entries of its own, so
+	// the notifiers are needed up front to tell what each still needs.
+	var notifiers []Notifier
+	sinkScope := rc.DedupScope == dedupScopeSink
+	var sinkSeen []seenEntries
+	if sinkScope {
+		if notifiers, err = newNotifiers(rc.Config, opts, report); err != nil {
+			return err
+		}
+		var sinks []string
+		for _, notifier := range notifiers {
+			sinks = append(sinks, sinkID(notifier))
+		}
+		state.adoptSinks(sinks)
+		for _, sink := range sinks {
+			sinkSeen = append(sinkSeen, state.Sinks[sink])
+		}
+	}
+	if len(state.Checkpoints) > 0 {
+		if rest := resumeAfter(filteredEntries, state.Checkpoints, seenKey); len(rest) != len(filteredEntries) {
+			skipped := len(filteredEntries) - len(rest)
+			logFields(fmt.Sprintf("Resuming an interrupted run after its checkpoint, skipping %d delivered entries.", skipped), "entry_count", skipped)
+			filteredEntries = rest
+		}
+	}
+	if rc.OverlapCheck && !diff {
+		checkSeenOverlap(filteredEntries, seen, rc.SeenKey, filters.DedupField, filters.LinkNormalization)
+	}
+	if diff {
+		// The diff takes the place of the seen check: entries still listed
+		// were posted before, and those no longer listed are reported.
+		filteredEntries = diffDigest(filteredEntries, state.Posted, seenKey)
+		if len(filteredEntries) == 0 {
+			infof("No change since the last digest, skipping it (DIGEST_MODE=diff).\n")
+			return nil
+		}
+	} else if sinkScope {
+		if unseen := filterUnseenByAny(filteredEntries, sinkSeen, rc.SeenKey, filters.DedupField, filters.LinkNormalization); len(unseen) != len(filteredEntries) {
+			skipped := len(filteredEntries) - len(unseen)
+			logFields(fmt.Sprintf("Skipping %d entries already sent to every notifier.", skipped), "entry_count", skipped)
+			filteredEntries = unseen
+		}
+	} else if unseen := filterUnseen(filteredEntries, seen, rc.SeenKey, filters.DedupField, filters.LinkNormalization); len(unseen) != len(filteredEntries) {
+		skipped := len(filteredEntries) - len(unseen)
+		logFields(fmt.Sprintf("Skipping %d already-notified entries.", skipped), "entry_count", skipped)
+		filteredEntries = unseen
+	}
+
+	// The stored time only moves on once a run has notified, so entries from
+	// a failed run are still newer than it next time.
+	nextLastRun := report.StartedAt
+	if rc.SinceLastRun && !diff {
+		switch {
+		case !lastRun.IsZero():
+			if recent := filterSinceLastRun(filteredEntries, lastRun); len(recent) != len(filteredEntries) {
+				skipped := len(filteredEntries) - len(recent)
+				logFields(fmt.Sprintf("Skipping %d entries published before the last run at %s.", skipped, lastRun.Format(time.RFC3339)),
+					"entry_count", skipped)
+				filteredEntries = recent
+			}
+			if recent := filterSinceNewest(filteredEntries, fetched, state.Newest, seenKey); len(recent) != len(filteredEntries) {
+				skipped := len(filteredEntries) - len(recent)
+				logFields(fmt.Sprintf("Skipping %d dateless entries listed after the newest one of the last run.", skipped), "entry_count", skipped)
+				filteredEntries = recent
+			}
+		case !rc.FirstRunSend:
+			infof("First run: recording the run time without sending %d entries (FIRST_RUN_SEND=false).\n", len(filteredEntries))
+			if opts.DryRun {
+				return nil
+			}
+			recordNewest(state.Newest, fetched, seenKey)
+			state.LastRun = nextLastRun
+			return saveState(stateFile, state)
+		}
+	}
+	advanceNewest := true
+
+	// Under DIGEST_MODE=daily-fresh a run only adds its unseen entries to
+	// those waiting in the state file until DIGEST_SEND_AT is due, when the
+	// digest lists all of them.
+	var dailyAt time.Time
+	if daily {
+		if dailyAt, err = lastSendAt(rc.DailySendAt, report.StartedAt.In(opts.Location)); err != nil {
+			return err
+		}
+		if state.Daily.LastSent.IsZero() {
+			state.Daily.LastSent = dailyAt // The first digest is the next one due
+		}
+		added := state.Daily.add(filteredEntries, seenKey)
+		if !dailyAt.After(state.Daily.LastSent) {
+			logFields(fmt.Sprintf("Holding %d new entries for the daily digest at %s, %d in all (DIGEST_MODE=daily-fresh).", added, rc.DailySendAt, len(state.Daily.Entries)),
+				"entry_count", added)
+			if opts.DryRun {
+				return nil
+			}
+			recordNewest(state.Newest, fetched, seenKey)
+			state.LastRun = nextLastRun
+			return saveState(stateFile, state)
+		}
+		filteredEntries = slices.Clone(state.Daily.Entries)
+		sortDaily(filteredEntries, feedURLs, rc.Ascending)
+		infof("Sending the daily digest due at %s.\n", dailyAt.Format(time.RFC3339))
+	}
+
+	if rc.VerifyLinks && len(filteredEntries) > 0 {
+		infof("Verifying %d entry links...\n", len(filteredEntries))
+		filteredEntries = verifyLinks(ctx, filteredEntries, rc.KeepUnreachable)
+	}
+	// Withheld entries never reach the seen state, so a later run sends them.
+	if limit := rc.MaxEntries; limit > 0 && len(filteredEntries) > limit {
+		withheld := len(filteredEntries) - limit
+		logFields(fmt.Sprintf("Withholding %d entries over the MAX_ENTRIES limit of %d until the next run.", withheld, limit),
+			"entry_count", withheld)
+		filteredEntries = filteredEntries[:limit]
+		nextLastRun = lastRun // Keep the withheld entries newer than the stored time
+		advanceNewest = false
+	}
+
+	// A flood is held back with the state left as it was, so its entries
+	// stay unseen until -release-pending sends them.
+	if guard := rc.Flood; guard.Enabled {
+		if guard.floods(len(filteredEntries), state.Matched) {
+			report.Held = len(filteredEntries)
+			warnf("Warning: holding %d entries, over %d times the recent average of %.1f per run (GUARD_AGAINST_FLOOD); check them in %s and run with -release-pending to send them\n",
+				len(filteredEntries), guard.Factor, state.Matched.Average, guard.PendingFile)
+			if opts.DryRun {
+				infof("Dry run: not writing %s.\n", guard.PendingFile)
+				return nil
+			}
+			return writePending(guard.PendingFile, pendingEntries{HeldAt: report.StartedAt, Average: state.Matched.Average, Entries: filteredEntries})
+		}
+		state.Matched.record(len(filteredEntries))
+		if len(filteredEntries) == 0 && !opts.DryRun {
+			// Quiet runs count towards the average too, though nothing else is saved.
+			if err := saveState(stateFile, state); err != nil {
+				return err
+			}
+		}
+	}
+	report.NewEntries = len(filteredEntries)
+
+	if rc.ArchiveDir != "" {
+		if err := writeArchive(rc.ArchiveDir, feedURLs, filteredEntries, time.Now(), rc.Archive); err != nil {
+			warnf("Warning: failed to archive entries: %v\n", err)
+			partialErrs = append(partialErrs, fmt.Errorf("error archiving entries: %w", err))
+		}
+	}
+
+	if len(filteredEntries) == 0 && len(state.Checkpoints) > 0 && !opts.DryRun {
+		// Nothing of an interrupted run is left to deliver.
+		clear(state.Checkpoints)
+		if err := saveState(stateFile, state); err != nil {
+			return err
+		}
+	}
+	if len(filteredEntries) == 0 && daily && !opts.DryRun {
+		// An empty digest is still the one due, so later entries wait for the next.
+		state.Daily = dailyDigest{LastSent: dailyAt}
+		if err := saveState(stateFile, state); err != nil {
+			return err
+		}
+	}
+	if len(filteredEntries) == 0 && !opts.NotifyOnEmpty && rc.Output == outputNotify {
+		infof("No new DNS-related articles found, or an error occurred that prevented finding any.\n")
+		return nil
+	}
+
+	// The HTML preview replaces delivery, so entries stay unseen until they
+	// are actually notified.
+	if rc.Output == outputHTML {
+		if err := writeHTMLDigest(rc.OutputFile, filteredEntries, opts, time.Now()); err != nil {
+			return err
+		}
+		infof("Wrote %d entries to %s instead of notifying.\n", len(filteredEntries), rc.OutputFile)
+		return nil
+	}
+
+	if notifiers == nil {
+		if notifiers, err = newNotifiers(rc.Config, opts, report); err != nil {
+			return err
+		}
+	}
+
+	if len(filteredEntries) == 0 {
+		infof("No new DNS-related articles found, sending heartbeat message.\n")
+		var errs []error
+		for _, notifier := range notifiers {
+			if err := notifier.Send(ctx, nil); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	logFields(fmt.Sprintf("Found %d DNS-related articles to send.", len(filteredEntries)), "entry_count", len(filteredEntries))
+
+	markSeen := func(entries []FilteredEntry) {
+		for _, entry := range entries {
+			seen.add(entry.FeedURL, seenKey(entry))
+			if rc.DedupWindow > 0 {
+				state.recordPublished(entry.FeedURL, seenKey(entry), entry.Published)
+			}
+		}
+	}
+
+	// Under CHECKPOINT_BATCH the entries go out in batches, each recorded in
+	// the state file once delivered, so an interrupted run resumes after the
+	// last one delivered rather than starting over.
+	batches := [][]FilteredEntry{filteredEntries}
+	if rc.CheckpointBatch > 0 && !opts.DryRun {
+		batches = slices.Collect(slices.Chunk(filteredEntries, rc.CheckpointBatch))
+	}
+	delivered := 0
+	for i, batch := range batches {
+		var errs []error
+		for _, notifier := range notifiers {
+			entries, sink := batch, sinkID(notifier)
+			if sinkScope {
+				if entries = filterUnseen(batch, state.Sinks[sink], rc.SeenKey, filters.DedupField, filters.LinkNormalization); len(entries) == 0 {
+					continue // Sent all of them by an earlier run
+				}
+			}
+			if err := notifier.Send(ctx, entries); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if sinkScope && !opts.DryRun {
+				for _, entry := range entries {
+					state.Sinks[sink].add(entry.FeedURL, seenKey(entry))
+				}
+			}
+		}
+		if len(errs) < len(notifiers) && !opts.DryRun {
+			count(ctx, runStats{Sent: len(batch)})
+		}
+
+		// Only mark entries as seen once delivery succeeded, so failures are
+		// retried on the next run. Under DEDUP_SCOPE=sink the notifiers that
+		// succeeded keep their progress, so only the others get them again,
+		// and a canvas created since is kept so it isn't created twice.
+		if err := errors.Join(errs...); err != nil {
+			if (sinkScope || !maps.Equal(canvases, state.Canvases)) && !opts.DryRun {
+				if saveErr := saveState(stateFile, state); saveErr != nil {
+					return errors.Join(err, saveErr)
+				}
+			}
+			return err
+		}
+		delivered += len(batch)
+		if i < len(batches)-1 {
+			markSeen(batch)
+			recordCheckpoints(state.Checkpoints, batch, fetched, seenKey)
+			if err := saveState(stateFile, state); err != nil {
+				return err
+			}
+			infof("Checkpoint: delivered %d of %d entries.\n", delivered, len(filteredEntries))
+		}
+	}
+	if opts.DryRun {
+		infof("Dry run: leaving %s unchanged.\n", stateFile)
+		return nil
+	}
+	markSeen(filteredEntries)
+	clear(state.Checkpoints) // Completed, so the next run starts afresh
+	if advanceNewest {
+		recordNewest(state.Newest, fetched, seenKey)
+	}
+	if diff {
+		state.Posted = applyDiff(state.Posted, filteredEntries, seenKey)
+	}
+	if daily {
+		// Entries withheld by MAX_ENTRIES are still unseen, so they wait again.
+		state.Daily = dailyDigest{LastSent: dailyAt}
+	}
+	state.LastRun = nextLastRun
+	if err := saveState(stateFile, state); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,1782 @@
+package rssnotify
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newFeedServer serves the named testdata fixture as an RSS feed.
+func newFeedServer(t *testing.T, fixture string) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile("testdata/" + fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// resolveConfig resolves cfg as Main does, failing the test on an error.
+func resolveConfig(t *testing.T, cfg Config) runConfig {
+	t.Helper()
+	rc, err := cfg.resolve()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	return rc
+}
+
+// newSlackServer fakes a Slack webhook that answers with status and records
+// every payload it receives.
+func newSlackServer(t *testing.T, status int) (*httptest.Server, *[]SlackMessage) {
+	t.Helper()
+	var payloads []SlackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+		}
+		var msg SlackMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Errorf("decoding Slack payload: %v", err)
+		}
+		payloads = append(payloads, msg)
+		w.WriteHeader(status)
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(srv.Close)
+
+	client := webhookClient
+	webhookClient = srv.Client()
+	t.Cleanup(func() { webhookClient = client })
+	return srv, &payloads
+}
+
+func TestFetchAndFilterRSSEntries(t *testing.T) {
+	srv := newFeedServer(t, "feed.xml")
+
+	entries, err := fetchAndFilterRSSEntries(t.Context(), srv.Client(), srv.URL, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatalf("fetchAndFilterRSSEntries: %v", err)
+	}
+
+	want := []FilteredEntry{
+		{Title: "Registry raises prices", Link: "https://domainincite.com/1"},
+		{Title: "Untitled Article", Link: "https://domainincite.com/2"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, entry := range entries {
+		if entry.Title != want[i].Title || entry.Link != want[i].Link {
+			t.Errorf("entry %d = %q %q, want %q %q", i, entry.Title, entry.Link, want[i].Title, want[i].Link)
+		}
+		if entry.Category != "dns" {
+			t.Errorf("entry %d category = %q, want dns", i, entry.Category)
+		}
+		if entry.Source != "Domain Incite" {
+			t.Errorf("entry %d source = %q, want Domain Incite", i, entry.Source)
+		}
+	}
+}
+
+func TestFetchAndFilterRSSEntriesDedupes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<rss version="2.0"><channel><title>x</title>
+<item><title>Registry raises prices</title><link>https://example.com/1</link><category>dns</category></item>
+<item><title>Registry raises prices (updated)</title><link> https://example.com/1 </link><category>dns</category></item>
+<item><title>Root zone grows</title><link>https://example.com/2</link><category>dns</category></item>
+</channel></rss>`)
+	}))
+	defer srv.Close()
+	counter := &runCounter{}
+	ctx := withSettings(t.Context(), runSettings{Counter: counter})
+
+	entries, err := fetchAndFilterRSSEntries(ctx, srv.Client(), srv.URL, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatalf("fetchAndFilterRSSEntries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Title != "Registry raises prices" || entries[1].Link != "https://example.com/2" {
+		t.Errorf("got %+v, want the first of each duplicate kept", entries)
+	}
+	if want := (runStats{Fetched: 3, Filtered: 3, Duplicates: 1}); counter.snapshot() != want {
+		t.Errorf("stats = %+v, want %+v", counter.snapshot(), want)
+	}
+}
+
+func TestRunStatsSummary(t *testing.T) {
+	s := runStats{Fetched: 42, Filtered: 5, Sent: 5, Duplicates: 1}
+	want := "SUMMARY fetched=42 filtered=5 sent=5 duplicates=1 skipped=0 duration=1.2s"
+	if got := s.summary(1200 * time.Millisecond); got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestFetchAndFilterRSSEntriesLocal(t *testing.T) {
+	abs, err := filepath.Abs("testdata/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := os.ReadFile(abs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := feedStdin
+	feedStdin = bytes.NewReader(body)
+	t.Cleanup(func() { feedStdin = stdin })
+
+	for _, feedURL := range []string{"./testdata/feed.xml", abs, "file://" + filepath.ToSlash(abs), "-"} {
+		entries, err := fetchAndFilterRSSEntries(t.Context(), nil, feedURL, filterOptions{Categories: defaultCategories})
+		if err != nil {
+			t.Errorf("%s: %v", feedURL, err)
+			continue
+		}
+		if len(entries) != 2 || entries[0].Link != "https://domainincite.com/1" || entries[0].FeedURL != feedURL {
+			t.Errorf("%s: got %+v, want the fixture's 2 dns entries", feedURL, entries)
+		}
+	}
+
+	if _, ok := localFeedPath("https://domainincite.com/feed"); ok {
+		t.Error("an https URL was treated as a local feed")
+	}
+}
+
+func TestFetchAndFilterRSSEntriesRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/feed", http.RedirectHandler("/moved/feed.xml", http.StatusMovedPermanently))
+	mux.HandleFunc("/moved/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<rss version="2.0"><channel><title>x</title>
+<item><title>Registry raises prices</title><link>article/1</link><category>dns</category></item>
+</channel></rss>`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	entries, err := fetchAndFilterRSSEntries(t.Context(), srv.Client(), srv.URL+"/feed", filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatalf("fetchAndFilterRSSEntries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if want := srv.URL + "/moved/article/1"; entries[0].Link != want {
+		t.Errorf("link = %q, want %q resolved against the final URL", entries[0].Link, want)
+	}
+	if want := srv.URL + "/feed"; entries[0].FeedURL != want {
+		t.Errorf("feed URL = %q, want the configured %q", entries[0].FeedURL, want)
+	}
+}
+
+func TestPermanentlyMoved(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/permanent", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	mux.Handle("/temporary", http.RedirectHandler("/permanent", http.StatusFound))
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/feed", false},
+		{"/permanent", true},
+		{"/temporary", false}, // One temporary hop means the old URL is still right
+	}
+	for _, tt := range tests {
+		resp, err := srv.Client().Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := permanentlyMoved(resp); got != tt.want {
+			t.Errorf("permanentlyMoved(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFetchFeedsKeepsFeedOrder(t *testing.T) {
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 1})
+	feed := func(delay time.Duration, link string) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			io.WriteString(w, `<rss version="2.0"><channel><title>x</title><item><title>t</title><link>`+link+`</link><category>dns</category></item></channel></rss>`)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	slow := feed(50*time.Millisecond, "https://example.com/slow")
+	fast := feed(0, "https://example.com/fast")
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	feedURLs := []string{slow.URL, broken.URL, fast.URL}
+	results := fetchFeeds(ctx, http.DefaultClient, feedURLs, filterOptions{Categories: defaultCategories}, 3, nil)
+	if len(results) != len(feedURLs) {
+		t.Fatalf("got %d results, want %d", len(results), len(feedURLs))
+	}
+	for i, result := range results {
+		if result.URL != feedURLs[i] {
+			t.Errorf("result %d is for %s, want %s", i, result.URL, feedURLs[i])
+		}
+	}
+	if len(results[0].Entries) != 1 || results[0].Entries[0].Link != "https://example.com/slow" {
+		t.Errorf("slow feed entries = %+v", results[0].Entries)
+	}
+	if results[1].Err == nil {
+		t.Error("expected the broken feed's error to be kept")
+	}
+	if len(results[2].Entries) != 1 || results[2].Entries[0].Link != "https://example.com/fast" {
+		t.Errorf("fast feed entries = %+v", results[2].Entries)
+	}
+}
+
+func TestFetchFeedsPerFeedSettings(t *testing.T) {
+	delay := retryBaseDelay
+	retryBaseDelay = 0
+	t.Cleanup(func() { retryBaseDelay = delay })
+	unavailable := func() *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	flaky, steady := unavailable(), unavailable()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 3})
+	feedURLs := []string{flaky.URL, steady.URL, slow.URL}
+	overrides := map[string]feedSettings{
+		flaky.URL: {MaxRetries: 1},
+		slow.URL:  {TimeoutSeconds: 1, MaxRetries: 1},
+	}
+	start := time.Now()
+	results := fetchFeeds(ctx, http.DefaultClient, feedURLs, filterOptions{Categories: defaultCategories}, 3, overrides)
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("fetching took %s, want the slow feed cut off at its own timeout", elapsed)
+	}
+	for i, want := range []int{1, 3, 1} {
+		if results[i].Err == nil || results[i].Attempts != want {
+			t.Errorf("%s: attempts = %d (error %v), want %d", feedURLs[i], results[i].Attempts, results[i].Err, want)
+		}
+	}
+
+	report := newRunReport(start)
+	report.recordFeed(results[1])
+	if got := report.Feeds[0]; got.URL != steady.URL || got.Attempts != 3 || got.Error == "" {
+		t.Errorf("feed report = %+v", got)
+	}
+}
+
+func TestRunSoftDeadline(t *testing.T) {
+	t.Setenv("STATE_FILE", t.TempDir()+"/seen.json")
+	t.Setenv("FETCH_CONCURRENCY", "1")
+	body, err := os.ReadFile("testdata/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first feed is still in flight when the deadline passes, so it
+	// finishes, but the second is never started.
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write(body)
+	}))
+	defer slow.Close()
+	var skippedRequests int
+	skipped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		skippedRequests++
+		w.Write(body)
+	}))
+	defer skipped.Close()
+	slack, payloads := newSlackServer(t, http.StatusOK)
+
+	ctx := withSettings(t.Context(), runSettings{SoftDeadline: newSoftDeadline(time.Now().Add(20 * time.Millisecond))})
+	cfg := Config{FeedURLs: []string{slow.URL, skipped.URL}, SlackWebhookURL: slack.URL}
+	report := newRunReport(time.Now())
+	if err := run(ctx, http.DefaultClient, resolveConfig(t, cfg), report); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if skippedRequests != 0 {
+		t.Errorf("got %d requests for the feed due after the soft deadline", skippedRequests)
+	}
+	if len(*payloads) != 1 || !strings.Contains((*payloads)[0].Text, "2 new articles") {
+		t.Errorf("payloads = %+v, want the in-flight feed's entries delivered", *payloads)
+	}
+	if got := report.Feeds[1]; got.Error != errSoftDeadline.Error() {
+		t.Errorf("skipped feed report = %+v", got)
+	}
+}
+
+func TestFetchFeedGzip(t *testing.T) {
+	want, err := os.ReadFile("testdata/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		if got := r.Header.Get("Accept"); got != defaultAcceptHeader {
+			t.Errorf("Accept = %q, want the default %q", got, defaultAcceptHeader)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write(want)
+		zw.Close()
+	}))
+	defer srv.Close()
+
+	got, _, err := fetchFeed(t.Context(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatalf("fetchFeed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes, want the %d byte decompressed feed", len(got), len(want))
+	}
+}
+
+// roundTripFunc adapts a function into an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestFetchFeedTruncatedBody(t *testing.T) {
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 2})
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Length", "1000")
+		io.WriteString(w, "<rss><channel>")
+	}))
+	defer srv.Close()
+
+	_, _, err := fetchFeed(ctx, srv.Client(), srv.URL)
+	if !errors.Is(err, errTruncatedResponse) {
+		t.Errorf("err = %v, want errTruncatedResponse", err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want the truncated download retried", requests)
+	}
+
+	// A transport that reports a clean EOF short of the declared length
+	// exercises the explicit Content-Length check.
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        make(http.Header),
+			ContentLength: 100,
+			Body:          io.NopCloser(strings.NewReader("<rss>")),
+			Request:       r,
+		}, nil
+	})}
+	_, _, err = fetchFeed(ctx, client, "https://domainincite.com/feed")
+	if !errors.Is(err, errTruncatedResponse) || !strings.Contains(err.Error(), "got 5 of 100 bytes") {
+		t.Errorf("err = %v, want the byte counts and errTruncatedResponse", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1000, "1.0 KB"},
+		{4200000, "4.2 MB"},
+		{250000000, "250 MB"},
+		{3000000000000000, "3000 TB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFetchFeedAcceptHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		want     string
+	}{
+		{"default", "", defaultAcceptHeader},
+		{"override", "application/feed+json", "application/feed+json"},
+	}
+	for _, tt := range tests {
+		ctx := withSettings(t.Context(), runSettings{AcceptHeader: tt.override})
+		var got string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get("Accept")
+			io.WriteString(w, "<rss/>")
+		}))
+		if _, _, err := fetchFeed(ctx, srv.Client(), srv.URL); err != nil {
+			t.Fatalf("%s: fetchFeed: %v", tt.name, err)
+		}
+		srv.Close()
+		if got != tt.want {
+			t.Errorf("%s: Accept = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchAndFilterRSSEntriesDoesNotRetryBadFeeds(t *testing.T) {
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 3})
+	tests := []struct {
+		name     string
+		encoding string
+		body     string
+	}{
+		{"not a feed", "", "<html><body>Not found</body></html>"},
+		{"corrupt gzip", "gzip", "not gzip at all"},
+	}
+	for _, tt := range tests {
+		requests := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if tt.encoding != "" {
+				w.Header().Set("Content-Encoding", tt.encoding)
+			}
+			io.WriteString(w, tt.body)
+		}))
+
+		_, err := fetchAndFilterRSSEntries(ctx, srv.Client(), srv.URL, filterOptions{Categories: defaultCategories})
+		srv.Close()
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if requests != 1 {
+			t.Errorf("%s: server received %d requests, want exactly 1", tt.name, requests)
+		}
+	}
+}
+
+func TestFetchFeedAuthorization(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "reader" || pass != "s3cret:x" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "<rss/>")
+	}))
+	defer srv.Close()
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 1})
+
+	if _, _, err := fetchFeed(ctx, srv.Client(), srv.URL); err == nil {
+		t.Error("expected an error without credentials")
+	}
+
+	auth, err := feedAuthorization("reader:s3cret:x", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = withSettings(t.Context(), runSettings{MaxRetries: 1, FeedAuthorization: auth})
+	if _, _, err := fetchFeed(ctx, srv.Client(), srv.URL); err != nil {
+		t.Errorf("fetchFeed with credentials: %v", err)
+	}
+}
+
+func TestFeedAuthorization(t *testing.T) {
+	if got, _ := feedAuthorization("", "tok"); got != "Bearer tok" {
+		t.Errorf("bearer header = %q", got)
+	}
+	if got, _ := feedAuthorization("", ""); got != "" {
+		t.Errorf("header without credentials = %q, want none", got)
+	}
+	if _, err := feedAuthorization("no-colon", ""); err == nil {
+		t.Error("expected an error for basic auth without a colon")
+	}
+	if _, err := feedAuthorization("a:b", "tok"); err == nil {
+		t.Error("expected an error when both are set")
+	}
+}
+
+func TestFetchAndFilterRSSEntriesErrorStatus(t *testing.T) {
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 1})
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	if _, err := fetchAndFilterRSSEntries(ctx, srv.Client(), srv.URL, filterOptions{Categories: defaultCategories}); err == nil {
+		t.Fatal("expected an error for a 404 feed")
+	}
+}
+
+func TestFetchFeedTLSVersionMismatch(t *testing.T) {
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 1})
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handshake succeeded below the minimum TLS version")
+	}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS11}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	_, _, err := fetchFeed(ctx, newFeedClient(tls.VersionTLS12, 0), srv.URL)
+	if err == nil {
+		t.Fatal("expected a TLS version error")
+	}
+	if !isTLSVersionError(err) || !strings.Contains(err.Error(), "check RSS_MIN_TLS_VERSION") {
+		t.Errorf("error = %v, want the TLS version hint", err)
+	}
+	if isTLSVersionError(errors.New("remote error: tls: protocol version not supported")) {
+		t.Error("matched an error by its text alone")
+	}
+}
+
+func TestFetchFeedCancelled(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// Cancelling during the first retry's backoff stops further attempts.
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(retryBaseDelay/10, cancel)
+	_, _, err := fetchFeed(ctx, srv.Client(), srv.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+func TestSendNotificationToSlack(t *testing.T) {
+	srv, payloads := newSlackServer(t, http.StatusOK)
+	entries := []FilteredEntry{
+		{Title: "Registry raises prices", Link: "https://domainincite.com/1"},
+		{Title: "Untitled Article", Link: "https://domainincite.com/2"},
+	}
+
+	if err := sendNotificationToSlack(t.Context(), srv.URL, entries, slackOptions{Messages: englishMessages}); err != nil {
+		t.Fatalf("sendNotificationToSlack: %v", err)
+	}
+
+	if len(*payloads) != 1 {
+		t.Fatalf("got %d payloads, want 1", len(*payloads))
+	}
+	msg := (*payloads)[0]
+	wantTypes := []string{"header", "divider", "section", "section"}
+	if len(msg.Blocks) != len(wantTypes) {
+		t.Fatalf("got %d blocks, want %d", len(msg.Blocks), len(wantTypes))
+	}
+	for i, block := range msg.Blocks {
+		if block.Type != wantTypes[i] {
+			t.Errorf("block %d type = %q, want %q", i, block.Type, wantTypes[i])
+		}
+	}
+	if got := msg.Blocks[2].Text.Text; got != "• <https://domainincite.com/1|Registry raises prices>" {
+		t.Errorf("first entry block = %q", got)
+	}
+	if !strings.Contains(msg.Text, "2 new articles") {
+		t.Errorf("fallback text = %q, want the article count", msg.Text)
+	}
+}
+
+func TestSendNotificationToSlackSplitsLargeDigests(t *testing.T) {
+	srv, payloads := newSlackServer(t, http.StatusOK)
+	entries := make([]FilteredEntry, 60)
+	for i := range entries {
+		entries[i] = FilteredEntry{Title: "Article", Link: "https://domainincite.com/"}
+	}
+
+	if err := sendNotificationToSlack(t.Context(), srv.URL, entries, slackOptions{Messages: englishMessages}); err != nil {
+		t.Fatalf("sendNotificationToSlack: %v", err)
+	}
+
+	if len(*payloads) != 2 {
+		t.Fatalf("got %d payloads, want 2", len(*payloads))
+	}
+	for i, msg := range *payloads {
+		if len(msg.Blocks) > maxSlackBlocks {
+			t.Errorf("payload %d has %d blocks, want at most %d", i, len(msg.Blocks), maxSlackBlocks)
+		}
+	}
+	if (*payloads)[0].Blocks[0].Type != "header" || (*payloads)[1].Blocks[0].Type == "header" {
+		t.Error("want the header on the first message only")
+	}
+}
+
+func TestSendNotificationToSlackPostDelay(t *testing.T) {
+	srv, payloads := newSlackServer(t, http.StatusOK)
+	entries := make([]FilteredEntry, 120)
+	for i := range entries {
+		entries[i] = FilteredEntry{Title: "Article", Link: "https://domainincite.com/"}
+	}
+	opts := slackOptions{Messages: englishMessages, PostDelay: 20 * time.Millisecond}
+
+	start := time.Now()
+	if err := sendNotificationToSlack(t.Context(), srv.URL, entries, opts); err != nil {
+		t.Fatalf("sendNotificationToSlack: %v", err)
+	}
+	if len(*payloads) != 3 {
+		t.Fatalf("got %d payloads, want 3", len(*payloads))
+	}
+	if elapsed := time.Since(start); elapsed < 2*opts.PostDelay {
+		t.Errorf("sent in %v, want at least %v between the 3 messages", elapsed, 2*opts.PostDelay)
+	}
+}
+
+func TestSendNotificationToSlackPostDelayCancelled(t *testing.T) {
+	srv, payloads := newSlackServer(t, http.StatusOK)
+	entries := make([]FilteredEntry, 60)
+	for i := range entries {
+		entries[i] = FilteredEntry{Title: "Article", Link: "https://domainincite.com/"}
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := sendNotificationToSlack(ctx, srv.URL, entries, slackOptions{Messages: englishMessages, PostDelay: time.Minute})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context's deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v, want the delay cut short by the context", elapsed)
+	}
+	if len(*payloads) != 1 {
+		t.Errorf("got %d payloads, want only the first message", len(*payloads))
+	}
+}
+
+func TestVerifyLinks(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/dead":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/get-only" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close() // Connections are refused
+
+	entries := []FilteredEntry{
+		{Title: "Live", Link: srv.URL + "/live"},
+		{Title: "Dead", Link: srv.URL + "/dead"},
+		{Title: "GET only", Link: srv.URL + "/get-only"},
+		{Title: "Unreachable", Link: closed.URL + "/gone"},
+	}
+	var titles []string
+	for _, entry := range verifyLinks(t.Context(), entries, true) {
+		titles = append(titles, entry.Title)
+	}
+	if want := []string{"Live", "GET only", "Unreachable"}; !slices.Equal(titles, want) {
+		t.Errorf("kept %v, want %v", titles, want)
+	}
+	if !slices.Contains(methods, "GET /get-only") || slices.Contains(methods, "GET /live") {
+		t.Errorf("requests = %v, want GET only after a 405 to HEAD", methods)
+	}
+	if got := verifyLinks(t.Context(), entries[3:], false); len(got) != 0 {
+		t.Errorf("kept %+v, want the unreachable entry dropped without VERIFY_LINKS_KEEP_UNREACHABLE", got)
+	}
+}
+
+func TestVerifyLinksCancelled(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	entries := []FilteredEntry{{Title: "A", Link: srv.URL + "/a"}}
+	if got := verifyLinks(ctx, entries, false); len(got) != 0 {
+		t.Errorf("kept %+v, want the unverifiable entry dropped", got)
+	}
+	if requests != 0 {
+		t.Errorf("server received %d requests after cancellation, want 0", requests)
+	}
+}
+
+func TestVerifyLinksRunDeadline(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	entries := []FilteredEntry{{Title: "A", Link: srv.URL + "/a"}}
+	if got := verifyLinks(ctx, entries, true); len(got) != 1 {
+		t.Errorf("kept %+v, want the entry kept as unreachable when the run's deadline expires", got)
+	}
+}
+
+func TestDiscoverFeedsCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("page fetched after cancellation")
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := discoverFeeds(ctx, srv.Client(), srv.URL); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestSendNotificationToSlackErrorStatus(t *testing.T) {
+	srv, _ := newSlackServer(t, http.StatusBadRequest)
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+
+	if err := sendNotificationToSlack(t.Context(), srv.URL, entries, slackOptions{Messages: englishMessages}); err == nil {
+		t.Fatal("expected an error for a 400 response")
+	}
+}
+
+func TestSendNotificationToSlackRetryAfter(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	client := webhookClient
+	webhookClient = srv.Client()
+	t.Cleanup(func() { webhookClient = client })
+
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+	start := time.Now()
+	if err := sendNotificationToSlack(t.Context(), srv.URL, entries, slackOptions{Messages: englishMessages}); err != nil {
+		t.Fatalf("sendNotificationToSlack: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+	if elapsed := time.Since(start); elapsed >= retryBaseDelay {
+		t.Errorf("took %s, want Retry-After: 0 to skip the backoff", elapsed)
+	}
+}
+
+func TestSoftDeadlineStopsRetriesAndLinkChecks(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 3, SoftDeadline: newSoftDeadline(time.Now())})
+
+	if _, err := doWithRetry(ctx, func() (*http.Response, error) { return http.Get(srv.URL) }); err == nil || requests != 1 {
+		t.Errorf("got %d requests (error %v), want the in-flight attempt only", requests, err)
+	}
+
+	entries := []FilteredEntry{{Title: "Unchecked", Link: srv.URL + "/article"}}
+	if got := verifyLinks(ctx, entries, false); len(got) != 1 || requests != 1 {
+		t.Errorf("verified = %+v after %d requests, want the entry kept unchecked", got, requests)
+	}
+}
+
+func TestDoWithRetrySkipsPermanentErrors(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+	client := newFeedClient(tls.VersionTLS13, 5*time.Second)
+	client.Transport.(*http.Transport).TLSClientConfig.RootCAs = srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	attempts := 0
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 3})
+	_, err := doWithRetry(ctx, func() (*http.Response, error) {
+		attempts++
+		return client.Get(srv.URL)
+	})
+	if err == nil {
+		t.Fatal("expected a TLS version error")
+	}
+	if attempts != 1 {
+		t.Errorf("made %d attempts, want the TLS version error not retried: %v", attempts, err)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: syscall.ECONNRESET}, true},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, true},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded}, true}, // Timeout
+		{fmt.Errorf("reading: %w", errTruncatedResponse), true},
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("tls: protocol version not supported")}, false},
+		{&url.Error{Op: "Get", URL: "://bad", Err: errors.New("missing protocol scheme")}, false},
+	}
+	for _, tt := range tests {
+		if got := isRetryableError(tt.err); got != tt.want {
+			t.Errorf("isRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	fallback := 500 * time.Millisecond
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", fallback},
+		{"3", 3 * time.Second},
+		{"3600", maxRetryAfter},
+		{"soon", fallback},
+		{"-1", fallback},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.value, fallback); got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSendNotificationToSlackNoEntries(t *testing.T) {
+	srv, payloads := newSlackServer(t, http.StatusOK)
+
+	if err := sendNotificationToSlack(t.Context(), srv.URL, nil, slackOptions{Messages: englishMessages}); err != nil {
+		t.Fatalf("sendNotificationToSlack: %v", err)
+	}
+	if len(*payloads) != 0 {
+		t.Errorf("got %d payloads, want none", len(*payloads))
+	}
+}
+
+func TestSendNotificationToSlackMissingWebhook(t *testing.T) {
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+
+	if err := sendNotificationToSlack(t.Context(), "", entries, slackOptions{Messages: englishMessages}); err == nil {
+		t.Fatal("expected an error without a webhook URL")
+	}
+}
+
+func TestEnvSeconds(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"45", 45 * time.Second},
+		{" 5 ", 5 * time.Second},
+		{"0", 0},
+		{"-3", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		t.Setenv("HTTP_TIMEOUT_SECONDS", tt.value)
+		if got := (Config{}).envSeconds("HTTP_TIMEOUT_SECONDS"); got != tt.want {
+			t.Errorf("envSeconds(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRunPartialFeedFailure(t *testing.T) {
+	ctx := withSettings(t.Context(), runSettings{MaxRetries: 1})
+	t.Setenv("STATE_FILE", t.TempDir()+"/seen.json")
+	feed := newFeedServer(t, "feed.xml")
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+	slack, payloads := newSlackServer(t, http.StatusOK)
+
+	cfg := Config{FeedURLs: []string{feed.URL, broken.URL}, SlackWebhookURL: slack.URL}
+	err := run(ctx, feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now()))
+	if err == nil || !strings.Contains(err.Error(), broken.URL) {
+		t.Fatalf("run error = %v, want the failed feed reported", err)
+	}
+	if len(*payloads) != 1 {
+		t.Errorf("got %d Slack payloads, want the working feed still delivered", len(*payloads))
+	}
+	state, err := loadState(os.Getenv("STATE_FILE"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Seen.has(feed.URL, "1") { // Keyed by the fixture's GUID
+		t.Error("delivered entries weren't marked as seen")
+	}
+}
+
+func TestRunMaxEntries(t *testing.T) {
+	t.Setenv("STATE_FILE", t.TempDir()+"/seen.json")
+	t.Setenv("MAX_ENTRIES", "1")
+	feed := newFeedServer(t, "feed.xml")
+	slack, payloads := newSlackServer(t, http.StatusOK)
+	cfg := Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL}
+
+	for i := 1; i <= 2; i++ {
+		if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		if len(*payloads) != i {
+			t.Fatalf("after run %d got %d Slack payloads, want %d", i, len(*payloads), i)
+		}
+		if got := (*payloads)[i-1].Text; !strings.Contains(got, "1 new articles") {
+			t.Errorf("run %d fallback text = %q, want a single entry", i, got)
+		}
+	}
+
+	// The entry withheld by the first run was sent by the second.
+	state, err := loadState(os.Getenv("STATE_FILE"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Seen.has(feed.URL, "1") || !state.Seen.has(feed.URL, "2") {
+		t.Errorf("seen = %v, want both entries marked after two runs", state.Seen)
+	}
+}
+
+func TestFilterSinceLastRun(t *testing.T) {
+	lastRun := time.Date(2025, 5, 15, 10, 0, 0, 0, time.UTC)
+	entries := []FilteredEntry{
+		{Title: "before", Published: lastRun.Add(-time.Hour)},
+		{Title: "at", Published: lastRun},
+		{Title: "after", Published: lastRun.Add(time.Minute)},
+		{Title: "undated"},
+	}
+	recent := filterSinceLastRun(entries, lastRun)
+	if len(recent) != 2 || recent[0].Title != "after" || recent[1].Title != "undated" {
+		t.Errorf("got %+v, want the newer and undated entries", recent)
+	}
+}
+
+func TestRunFirstRunSend(t *testing.T) {
+	state := t.TempDir() + "/seen.json"
+	t.Setenv("STATE_FILE", state)
+	t.Setenv("SINCE_LAST_RUN", "true")
+	t.Setenv("FIRST_RUN_SEND", "false")
+	feed := newFeedServer(t, "feed.xml")
+	slack, payloads := newSlackServer(t, http.StatusOK)
+	cfg := Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL}
+
+	start := time.Now()
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(start)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(*payloads) != 0 {
+		t.Errorf("got %d Slack payloads, want nothing sent on the first run", len(*payloads))
+	}
+	stored, err := loadState(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Seen) != 0 || !stored.LastRun.Equal(start) {
+		t.Errorf("state = %v %s, want only the run time %s recorded", stored.Seen, stored.LastRun, start)
+	}
+}
+
+func TestLoadSeenMigratesFlatState(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "seen.json")
+	legacy := `{"seen": ["1", "https://example.com/2"], "last_run": "2025-05-15T10:00:00Z"}`
+	if err := os.WriteFile(state, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	feeds := []string{"https://example.com/a.xml", "https://example.com/b.xml"}
+
+	stored, err := loadState(state, feeds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := stored.Seen
+	for _, feedURL := range feeds {
+		if !seen.has(feedURL, "1") || !seen.has(feedURL, "https://example.com/2") {
+			t.Errorf("seen[%s] = %v, want the legacy keys migrated", feedURL, seen[feedURL])
+		}
+	}
+	if want := time.Date(2025, 5, 15, 10, 0, 0, 0, time.UTC); !stored.LastRun.Equal(want) {
+		t.Errorf("last run = %s, want %s", stored.LastRun, want)
+	}
+
+	if err := saveState(state, stored); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"seen"`) {
+		t.Errorf("saved state still has the flat list:\n%s", data)
+	}
+	reloaded, err := loadState(state, []string{"https://example.com/c.xml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.Seen, seen) {
+		t.Errorf("reloaded = %v, want %v unchanged by a new feed", reloaded, seen)
+	}
+}
+
+func TestRunDedupWindow(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "seen.json")
+	t.Setenv("STATE_FILE", state)
+	t.Setenv("DEDUP_WINDOW_DAYS", "36500")
+	feed := newFeedServer(t, "authors.xml")
+	slack, payloads := newSlackServer(t, http.StatusOK)
+	initial := fmt.Sprintf(`{"feeds": {%[1]q: ["https://old.example.com/1", "https://domainincite.com/3"]},
+		"published": {%[1]q: {"https://old.example.com/1": "1900-01-01T00:00:00Z"}}}`, feed.URL)
+	if err := os.WriteFile(state, []byte(initial), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL}
+	rc := resolveConfig(t, cfg)
+	if rc.Filters.MaxAge != 36500*24*time.Hour {
+		t.Errorf("MaxAge = %s, want it capped at the dedup window", rc.Filters.MaxAge)
+	}
+	if err := run(t.Context(), feed.Client(), rc, newRunReport(time.Now())); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(*payloads) != 1 || !strings.Contains((*payloads)[0].Text, "2 new articles") {
+		t.Errorf("payloads = %+v, want the two unseen entries", *payloads)
+	}
+
+	stored, err := loadState(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Seen.has(feed.URL, "https://old.example.com/1") {
+		t.Error("kept a seen entry published before the window")
+	}
+	if !stored.Seen.has(feed.URL, "https://domainincite.com/3") {
+		t.Error("pruned a seen entry without a recorded publication date")
+	}
+	published := stored.Published[feed.URL]
+	if want := time.Date(2024, time.June, 1, 8, 0, 0, 0, time.UTC); len(published) != 1 || !published["https://domainincite.com/1"].Equal(want) {
+		t.Errorf("published = %v, want only the dated entry recorded", published)
+	}
+}
+
+func TestAdoptSinks(t *testing.T) {
+	feedURL := "https://example.com/feed.xml"
+	state := runState{Seen: seenEntries{feedURL: {"https://example.com/1": true}}, Sinks: map[string]seenEntries{}}
+
+	state.adoptSinks([]string{"slack"})
+	if !state.Sinks["slack"].has(feedURL, "https://example.com/1") {
+		t.Error("first sink didn't take over the global seen entries")
+	}
+
+	state.adoptSinks([]string{"slack", "webhook"})
+	if state.Sinks["webhook"] == nil || len(state.Sinks["webhook"]) != 0 {
+		t.Errorf("webhook sink = %v, want it to start empty once sinks are tracked", state.Sinks["webhook"])
+	}
+}
+
+func TestRunDedupScopeSink(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "seen.json")
+	t.Setenv("STATE_FILE", state)
+	t.Setenv("DEDUP_SCOPE", "sink")
+	feed := newFeedServer(t, "authors.xml")
+	slack, payloads := newSlackServer(t, http.StatusOK)
+
+	cfg := Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL}
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if len(*payloads) != 1 {
+		t.Fatalf("slack payloads = %d, want 1", len(*payloads))
+	}
+
+	var received []FilteredEntry
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entries []FilteredEntry
+		if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		received = append(received, entries...)
+	}))
+	t.Cleanup(webhook.Close)
+
+	cfg.GenericWebhookURL = webhook.URL
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if len(*payloads) != 1 {
+		t.Errorf("slack payloads = %d, want nothing sent again", len(*payloads))
+	}
+	if len(received) != 3 {
+		t.Errorf("webhook received %d entries, want the 3 slack was sent", len(received))
+	}
+
+	stored, err := loadState(state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sink := range []string{"slack", "webhook"} {
+		if got := len(stored.Sinks[sink][feed.URL]); got != 3 {
+			t.Errorf("%s sink has %d seen entries, want 3", sink, got)
+		}
+	}
+}
+
+func TestFilterUnseenPerFeed(t *testing.T) {
+	seen := seenEntries{"https://example.com/a.xml": {"https://example.com/shared": true}}
+	entries := []FilteredEntry{
+		{Link: "https://example.com/shared", FeedURL: "https://example.com/a.xml"},
+		{Link: "https://example.com/shared", FeedURL: "https://example.com/b.xml"},
+	}
+	unseen := filterUnseen(entries, seen, seenKeyGUID, dedupGUID, normalizeBasic)
+	if len(unseen) != 1 || unseen[0].FeedURL != "https://example.com/b.xml" {
+		t.Errorf("got %+v, want only the other feed's entry", unseen)
+	}
+}
+
+func TestCheckSeenOverlap(t *testing.T) {
+	a, b := "https://example.com/a.xml", "https://example.com/b.xml"
+	entries := []FilteredEntry{
+		{Link: "https://example.com/1", GUID: "1", FeedURL: a},
+		{Link: "https://example.com/2", GUID: "2", FeedURL: a},
+		{Link: "https://example.com/9", GUID: "9", FeedURL: b},
+	}
+	tests := []struct {
+		name string
+		seen seenEntries
+		want []string
+	}{
+		{"empty state", seenEntries{}, nil},
+		{"overlap", seenEntries{a: {"1": true}, b: {"9": true}}, nil},
+		{"legacy link", seenEntries{a: {"https://example.com/2": true}, b: {"9": true}}, nil},
+		{"GUIDs changed", seenEntries{a: {"old-1": true, "old-2": true}, b: {"9": true}}, []string{a}},
+		{"URL changed", seenEntries{"https://example.com/old.xml": {"3": true}}, []string{a, b}},
+		{"renamed feed matches another's entries", seenEntries{"https://example.com/old.xml": {"9": true}, a: {"1": true}}, nil},
+	}
+	for _, tt := range tests {
+		got := checkSeenOverlap(entries, tt.seen, seenKeyGUID, dedupGUID, normalizeBasic)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: checkSeenOverlap = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilteredEntryKey(t *testing.T) {
+	tests := []struct {
+		entry FilteredEntry
+		want  string
+	}{
+		{FilteredEntry{Link: "https://example.com/1?utm_source=rss", GUID: " urn:1 "}, "urn:1"},
+		{FilteredEntry{Link: " https://example.com/1 "}, "https://example.com/1"},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.key(dedupGUID, normalizeOff); got != tt.want {
+			t.Errorf("key() of %+v = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestFilterUnseenAcceptsLegacyLinks(t *testing.T) {
+	entries := []FilteredEntry{
+		{Link: "https://example.com/1", GUID: "1"},
+		{Link: "https://example.com/2", GUID: "2"},
+		{Link: "https://example.com/3", GUID: "3"},
+	}
+	seen := seenEntries{"": {"1": true, "https://example.com/2": true}}
+
+	unseen := filterUnseen(entries, seen, seenKeyGUID, dedupGUID, normalizeBasic)
+	if len(unseen) != 1 || unseen[0].GUID != "3" {
+		t.Errorf("got %+v, want only the third entry", unseen)
+	}
+}
+
+func TestFilterUnseenByHash(t *testing.T) {
+	seen := seenEntries{}
+	latest := FilteredEntry{Title: "Latest news", Link: "https://example.com/latest", Description: "Monday's update"}
+	seen.add("", latest.seenKey(seenKeyHash, dedupGUID, normalizeBasic))
+
+	updated := latest
+	updated.Description = "Tuesday's update"
+	unseen := filterUnseen([]FilteredEntry{latest, updated}, seen, seenKeyHash, dedupGUID, normalizeBasic)
+	if len(unseen) != 1 || unseen[0].Description != "Tuesday's update" {
+		t.Errorf("got %+v, want only the changed content", unseen)
+	}
+	if key := latest.seenKey(seenKeyHash, dedupGUID, normalizeBasic); !strings.HasPrefix(key, "sha256:") || key == updated.seenKey(seenKeyHash, dedupGUID, normalizeBasic) {
+		t.Errorf("hash key = %q, want a sha256: key differing with the content", key)
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	base, err := url.Parse("https://example.com/feeds/dns.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		link, want string
+	}{
+		{"https://other.example/a", "https://other.example/a"},
+		{"/2024/dns-news", "https://example.com/2024/dns-news"},
+		{"article?id=1", "https://example.com/feeds/article?id=1"},
+		{"//cdn.example/a", "https://cdn.example/a"},
+		{"http://[::1", "http://[::1"}, // Unparseable, kept as-is
+	}
+	for _, tt := range tests {
+		if got := resolveLink(base, tt.link); got != tt.want {
+			t.Errorf("resolveLink(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestFaviconURL(t *testing.T) {
+	for link, want := range map[string]string{
+		"https://domainincite.com/":          "https://domainincite.com/favicon.ico",
+		" http://example.com:8080/blog?x=1 ": "http://example.com:8080/favicon.ico",
+		"/relative/path":                     "",
+		"ftp://example.com/feed":             "",
+		"":                                   "",
+	} {
+		if got := faviconURL(link); got != want {
+			t.Errorf("faviconURL(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestFavicons(t *testing.T) {
+	feed := newFeedServer(t, "feed.xml")
+	entries, err := fetchAndFilterRSSEntries(t.Context(), feed.Client(), feed.URL, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries { // From the channel <link>
+		if entry.FaviconURL != "https://domainincite.com/favicon.ico" {
+			t.Errorf("%q favicon = %q, want the channel site's", entry.Title, entry.FaviconURL)
+		}
+	}
+
+	entry := FilteredEntry{Title: "Registry raises prices", Link: "https://domainincite.com/1", FaviconURL: "https://domainincite.com/favicon.ico"}
+	if got := entryAccessory(entry, slackOptions{}); got != nil {
+		t.Errorf("accessory without SLACK_SHOW_FAVICON = %+v, want none", got)
+	}
+	opts := slackOptions{Messages: englishMessages, ShowFavicon: true}
+	if got := entryAccessory(entry, opts); got == nil || got.ImageURL != entry.FaviconURL {
+		t.Errorf("accessory = %+v, want the favicon", got)
+	}
+	withImage := entry
+	withImage.ImageURL = "https://domainincite.com/1.jpg"
+	if got := entryAccessory(withImage, opts); got == nil || got.ImageURL != withImage.ImageURL {
+		t.Errorf("accessory = %+v, want the article image over the favicon", got)
+	}
+	chat := buildGoogleChatMessages([]FilteredEntry{entry}, opts)
+	widgets := chat[0].CardsV2[0].Card.Sections[0].Widgets
+	if icon := widgets[0].DecoratedText.StartIcon; icon == nil || icon.IconURL != entry.FaviconURL {
+		t.Errorf("Google Chat widget = %+v, want the favicon as its start icon", widgets[0].DecoratedText)
+	}
+}
+
+func TestFormatEntryLineEnclosure(t *testing.T) {
+	entry := FilteredEntry{Title: "Episode 1", Link: "https://example.com/ep1"}
+	if got, want := formatEntryLine(entry, slackOptions{}), "• <https://example.com/ep1|Episode 1>"; got != want {
+		t.Errorf("without enclosure = %q, want %q", got, want)
+	}
+
+	entry.Enclosure = &Enclosure{URL: "https://example.com/ep1.mp3", Type: "audio/mpeg", Length: "1024"}
+	want := "• <https://example.com/ep1|Episode 1>\n🎧 <https://example.com/ep1.mp3|Listen (audio/mpeg)>"
+	if got := formatEntryLine(entry, slackOptions{}); got != want {
+		t.Errorf("with enclosure = %q, want %q", got, want)
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 5, d, 0, 0, 0, 0, time.UTC) }
+	entries := func() []FilteredEntry {
+		return []FilteredEntry{
+			{Title: "undated-a"},
+			{Title: "2nd", Published: day(2)},
+			{Title: "undated-b"},
+			{Title: "3rd", Published: day(3)},
+			{Title: "1st", Published: day(1)},
+		}
+	}
+	titles := func(entries []FilteredEntry) string {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Title)
+		}
+		return strings.Join(names, ",")
+	}
+
+	desc := entries()
+	sortEntries(desc, false)
+	if got, want := titles(desc), "3rd,2nd,1st,undated-a,undated-b"; got != want {
+		t.Errorf("desc = %s, want %s", got, want)
+	}
+	asc := entries()
+	sortEntries(asc, true)
+	if got, want := titles(asc), "1st,2nd,3rd,undated-a,undated-b"; got != want {
+		t.Errorf("asc = %s, want %s", got, want)
+	}
+}
+
+func TestRankDateless(t *testing.T) {
+	body, err := os.ReadFile("testdata/dateless.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rankDateless(entries)
+	sortEntries(entries, true)
+	if got := []string{entries[0].GUID, entries[1].GUID, entries[2].GUID}; !slices.Equal(got, []string{"note-1", "note-2", "note-3"}) {
+		t.Errorf("ascending = %v, want the last listed (oldest) first", got)
+	}
+	sortEntries(entries, false)
+	if entries[0].GUID != "note-3" || entries[0].Rank != 1 {
+		t.Errorf("descending starts with %+v, want the first listed", entries[0])
+	}
+
+	dated := []FilteredEntry{{Title: "undated"}, {Title: "dated", Published: time.Now()}}
+	rankDateless(dated)
+	if dated[0].Rank != 0 {
+		t.Error("ranked a feed with dated entries")
+	}
+}
+
+func TestRunDatelessFeedOrder(t *testing.T) {
+	t.Setenv("SINCE_LAST_RUN", "true")
+	t.Setenv("FIRST_RUN_SEND", "false")
+	original, err := os.ReadFile("testdata/dateless.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer := strings.Replace(string(original), "<item>", `<item>
+      <title>Registry fees</title>
+      <link>https://notes.example.com/4</link>
+      <guid isPermaLink="false">note-4</guid>
+      <category>dns</category>
+    </item>
+    <item>`, 1)
+
+	for _, tt := range []struct {
+		ordered bool
+		want    string
+	}{{true, "1 new articles"}, {false, "4 new articles"}} {
+		t.Setenv("STATE_FILE", t.TempDir()+"/seen.json")
+		t.Setenv("DATELESS_FEED_ORDER", strconv.FormatBool(tt.ordered))
+		body := string(original)
+		feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, body)
+		}))
+		defer feed.Close()
+		slack, payloads := newSlackServer(t, http.StatusOK)
+		cfg := Config{FeedURLs: []string{feed.URL}, SlackWebhookURL: slack.URL}
+
+		// The first run only records state; the second sees a new item listed first.
+		if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+			t.Fatalf("first run: %v", err)
+		}
+		body = newer
+		if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), newRunReport(time.Now())); err != nil {
+			t.Fatalf("second run: %v", err)
+		}
+		if len(*payloads) != 1 || !strings.Contains((*payloads)[0].Text, tt.want) {
+			t.Errorf("DATELESS_FEED_ORDER=%t: payloads = %+v, want %s", tt.ordered, *payloads, tt.want)
+		}
+	}
+}
+
+func TestEscapeMrkdwn(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Registry raises prices", "Registry raises prices"},
+		{"AT&T <sponsored> news", "AT&amp;T &lt;sponsored&gt; news"},
+		{"&amp;", "&amp;amp;"}, // Already-escaped text is shown literally
+	}
+	for _, tt := range tests {
+		if got := escapeMrkdwn(tt.in); got != tt.want {
+			t.Errorf("escapeMrkdwn(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSendNotificationToSlackEscapesTitles(t *testing.T) {
+	srv, payloads := newSlackServer(t, http.StatusOK)
+	entries := []FilteredEntry{{Title: "Q&A: <.dev> > .app", Link: "https://domainincite.com/1?a=1&b=2"}}
+
+	if err := sendNotificationToSlack(t.Context(), srv.URL, entries, slackOptions{Messages: englishMessages}); err != nil {
+		t.Fatalf("sendNotificationToSlack: %v", err)
+	}
+	msg := (*payloads)[0]
+	link := "<https://domainincite.com/1?a=1&b=2|Q&amp;A: &lt;.dev&gt; &gt; .app>"
+	if got, want := msg.Blocks[2].Text.Text, "• "+link; got != want {
+		t.Errorf("entry block = %q, want %q", got, want)
+	}
+	if !strings.Contains(msg.Text, link) {
+		t.Errorf("fallback text = %q, want it to contain %q", msg.Text, link)
+	}
+}
+
+func TestBuildSlackMessageCategoryColors(t *testing.T) {
+	entries := []FilteredEntry{
+		{Title: "Registry raises prices", Link: "https://domainincite.com/1", Category: "DNS"},
+		{Title: "Registrar breached", Link: "https://domainincite.com/2", Category: "security"},
+		{Title: "New gTLD launches", Link: "https://domainincite.com/3", Category: "dns"},
+		{Title: "Keyword match", Link: "https://domainincite.com/4"},
+	}
+	opts := slackOptions{
+		Messages:       englishMessages,
+		CategoryColors: map[string]string{"dns": "#36a64f", "security": "#e01e5a"},
+	}
+
+	msg := buildSlackMessage(entries, opts)
+	if len(msg.Blocks) != 2 {
+		t.Errorf("got %d top-level blocks, want only the header and divider", len(msg.Blocks))
+	}
+	want := []struct {
+		color  string
+		blocks int
+	}{{"#36a64f", 3}, {"#e01e5a", 2}, {"", 1}}
+	if len(msg.Attachments) != len(want) {
+		t.Fatalf("got %d attachments, want %d: %+v", len(msg.Attachments), len(want), msg.Attachments)
+	}
+	for i, w := range want {
+		if got := msg.Attachments[i]; got.Color != w.color || len(got.Blocks) != w.blocks {
+			t.Errorf("attachment %d = color %q with %d blocks, want %q with %d", i, got.Color, len(got.Blocks), w.color, w.blocks)
+		}
+	}
+	if got := msg.Attachments[0].Blocks[0]; got.Type != "context" || got.Elements[0].Text != "*DNS*" {
+		t.Errorf("group label = %+v, want a context block naming the category", got)
+	}
+	if got := msg.Attachments[0].Blocks[2].Text.Text; !strings.Contains(got, "New gTLD launches") {
+		t.Errorf("dns group entry = %q, want the second dns entry", got)
+	}
+}
+
+func TestBuildSlackMessageDiscussLink(t *testing.T) {
+	body, err := os.ReadFile("testdata/comments.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://domainincite.com/1/feed", "https://domainincite.com/2#comments", ""}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.DiscussLink != want[i] {
+			t.Errorf("entry %d discuss link = %q, want %q", i, entry.DiscussLink, want[i])
+		}
+	}
+
+	text := func(opts slackOptions) string {
+		var b strings.Builder
+		for _, block := range buildSlackMessage(entries, opts).Blocks {
+			if block.Text != nil {
+				b.WriteString(block.Text.Text + "\n")
+			}
+		}
+		return b.String()
+	}
+	shown := text(slackOptions{Messages: englishMessages, ShowDiscussLink: true})
+	if !strings.Contains(shown, "(<https://domainincite.com/1/feed|discuss>)") || !strings.Contains(shown, "(<https://domainincite.com/2#comments|discuss>)") {
+		t.Errorf("blocks missing the discuss links:\n%s", shown)
+	}
+	if strings.Count(shown, "|discuss>") != 2 {
+		t.Errorf("want a discuss link only on entries with comments:\n%s", shown)
+	}
+	if hidden := text(slackOptions{Messages: englishMessages}); strings.Contains(hidden, "discuss") {
+		t.Errorf("discuss links shown without ShowDiscussLink:\n%s", hidden)
+	}
+}
+
+func TestBuildSlackMessagePriorityMention(t *testing.T) {
+	entries := []FilteredEntry{
+		{Title: "Routine news", Link: "https://domainincite.com/1"},
+		{Title: "Registry outage", Link: "https://domainincite.com/2", Priority: true},
+	}
+	opts := slackOptions{Messages: englishMessages, PriorityMention: "<!here>"}
+
+	msg := buildSlackMessage(entries, opts)
+	if got := msg.Blocks[0]; got.Type != "section" || got.Text.Text != "<!here>" {
+		t.Errorf("first block = %+v, want the mention section", got)
+	}
+	if msg.Blocks[1].Type != "header" {
+		t.Errorf("second block type = %q, want the header after the mention", msg.Blocks[1].Type)
+	}
+	if !strings.HasPrefix(msg.Text, "<!here> ") {
+		t.Errorf("fallback text = %q, want it prefixed with the mention", msg.Text)
+	}
+
+	routine := buildSlackMessage(entries[:1], opts)
+	if routine.Blocks[0].Type != "header" || strings.Contains(routine.Text, "<!here>") {
+		t.Errorf("routine digest = %+v, want no mention without a priority entry", routine)
+	}
+}
+
+func TestBuildSlackMessageLayouts(t *testing.T) {
+	entries := []FilteredEntry{
+		{Title: "Registry raises prices", Link: "https://domainincite.com/1"},
+		{Title: "Root zone grows", Link: "https://domainincite.com/2"},
+	}
+	tests := map[string]string{
+		"":               "header divider entry entry",
+		"SUMMARY-FIRST":  "header summary divider entry entry",
+		" summary-last ": "header divider entry entry divider summary",
+		"entries-only":   "entry entry",
+		"something-else": "header divider entry entry", // Falls back to the default
+	}
+	for value, want := range tests {
+		opts := slackOptions{Messages: englishMessages, Layout: parseLayout(value)}
+		var got []string
+		for _, block := range buildSlackMessage(entries, opts).Blocks {
+			switch {
+			case block.Type != "section":
+				got = append(got, block.Type)
+			case block.Text.Text == "*2* new articles":
+				got = append(got, "summary")
+			default:
+				got = append(got, "entry")
+			}
+		}
+		if strings.Join(got, " ") != want {
+			t.Errorf("SLACK_LAYOUT=%q: blocks = %v, want %s", value, got, want)
+		}
+	}
+}
+
+func TestHeaderDate(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	cfg := Config{Env: map[string]string{"SLACK_HEADER_DATE_FORMAT": "2 January 2006", "DISPLAY_TIMEZONE": "Asia/Tokyo"}}
+	before := time.Now().In(tokyo).Format("2 January 2006")
+	got := resolveConfig(t, cfg).Slack.headerText()
+	after := time.Now().In(tokyo).Format("2 January 2006") // In case midnight passed
+	if prefix := "📰 Daily DNS News Digest (Domain Incite) — "; got != prefix+before && got != prefix+after {
+		t.Errorf("header = %q, want %q", got, prefix+before)
+	}
+
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+	opts := slackOptions{Messages: englishMessages, HeaderDate: "1 June 2024"}
+	want := "📰 Daily DNS News Digest (Domain Incite) — 1 June 2024"
+	if got := buildSlackMessage(entries, opts).Blocks[0].Text.Text; got != want {
+		t.Errorf("Slack header = %q, want %q", got, want)
+	}
+	if got := buildTeamsMessages(entries, opts)[0].Attachments[0].Content.Body[0].Text; got != want {
+		t.Errorf("Teams header = %q, want %q", got, want)
+	}
+	if got := resolveConfig(t, Config{}).Slack.headerText(); got != "📰 Daily DNS News Digest (Domain Incite)" {
+		t.Errorf("header without SLACK_HEADER_DATE_FORMAT = %q, want no date", got)
+	}
+}
+
+func TestFormatEntryLineCategoryEmoji(t *testing.T) {
+	body, err := os.ReadFile("testdata/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := filterRSSEntries(body, filterOptions{Categories: []string{"dns", "gTLDs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Env: map[string]string{"CATEGORY_EMOJI": "DNS=🌐, gtlds=:globe_with_meridians:, malformed"}}
+	opts := resolveConfig(t, cfg).Slack
+	want := map[string]string{
+		"Registry raises prices": "🌐 <",
+		"New gTLD launches":      ":globe_with_meridians: <",
+	}
+	for _, entry := range entries {
+		prefix, ok := want[entry.Title]
+		if !ok {
+			continue
+		}
+		if line := formatEntryLine(entry, opts); !strings.HasPrefix(line, prefix) {
+			t.Errorf("%q line = %q, want it to start with %q", entry.Title, line, prefix)
+		}
+		delete(want, entry.Title)
+	}
+	if len(want) != 0 {
+		t.Errorf("entries %v not matched", want)
+	}
+	if line := formatEntryLine(FilteredEntry{Title: "Other", Link: "https://example.com", Category: "ipv6"}, opts); !strings.HasPrefix(line, "• <") {
+		t.Errorf("line without an emoji = %q, want the bullet", line)
+	}
+}
+
+func TestBuildSlackMessageRichEntries(t *testing.T) {
+	body, err := os.ReadFile("testdata/authors.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := filterRSSEntries(body, filterOptions{Categories: defaultCategories})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := []string{entries[0].Author, entries[1].Author, entries[2].Author}; !slices.Equal(got, []string{"Kevin Murphy", "Jane Doe", ""}) {
+		t.Fatalf("authors = %q", got)
+	}
+
+	now := time.Date(2024, time.June, 1, 11, 0, 0, 0, time.UTC)
+	opts := slackOptions{Messages: englishMessages, Layout: layoutEntriesOnly, RichEntries: true, ShowFavicon: true, Now: now, Location: time.UTC}
+	blocks := buildSlackMessage(entries, opts).Blocks
+	var types []string
+	for _, block := range blocks {
+		types = append(types, block.Type)
+	}
+	// The last entry has no author or date, but still shows its favicon.
+	if want := []string{"section", "context", "section", "context", "section", "context"}; !slices.Equal(types, want) {
+		t.Fatalf("blocks = %v, want %v", types, want)
+	}
+	if got := blocks[0].Text.Text; strings.Contains(got, "3h ago") || blocks[0].Accessory != nil {
+		t.Errorf("section = %q (accessory %+v), want the date and favicon moved to the context block", got, blocks[0].Accessory)
+	}
+	context := blocks[1].Elements
+	if len(context) != 2 || context[0].Type != "image" || context[0].ImageURL != "https://domainincite.com/favicon.ico" ||
+		context[1].Text != "Kevin Murphy · Domain Incite · 3h ago" {
+		t.Errorf("context = %+v", context)
+	}
+
+	opts.CategoryEmoji = map[string]string{"dns": "🌐"}
+	if got := buildSlackMessage(entries, opts).Blocks[1].Elements[0]; got.Text != "🌐" {
+		t.Errorf("context icon = %+v, want the category emoji", got)
+	}
+	opts = slackOptions{Messages: englishMessages, Layout: layoutEntriesOnly}
+	if got := len(buildSlackMessage(entries, opts).Blocks); got != 3 {
+		t.Errorf("got %d blocks without SLACK_RICH_ENTRIES, want one section per entry", got)
+	}
+}
+
+func TestSplitSlackMessageKeepsRichEntriesTogether(t *testing.T) {
+	entries := make([]FilteredEntry, 60)
+	for i := range entries {
+		entries[i] = FilteredEntry{Title: fmt.Sprintf("Entry %d", i), Link: fmt.Sprintf("https://domainincite.com/%d", i), Source: "Domain Incite"}
+	}
+	check := func(name string, chunks []SlackMessage) {
+		t.Helper()
+		total := 0
+		for i, chunk := range chunks {
+			blocks := chunk.Blocks
+			for _, attachment := range chunk.Attachments {
+				blocks = append(blocks, attachment.Blocks...)
+			}
+			if len(blocks) > maxSlackBlocks {
+				t.Errorf("%s: message %d has %d blocks", name, i, len(blocks))
+			}
+			if len(blocks) > 0 && blocks[len(blocks)-1].Type == "section" && blocks[len(blocks)-1].Text.Text != "" && i < len(chunks)-1 {
+				t.Errorf("%s: message %d ends with an entry whose context block moved to the next message", name, i)
+			}
+			total += len(blocks)
+		}
+		if want := 2*len(entries) + 2; total != want && name == "blocks" {
+			t.Errorf("%s: got %d blocks in total, want %d", name, total, want)
+		}
+	}
+
+	opts := slackOptions{Messages: englishMessages, RichEntries: true}
+	check("blocks", splitSlackMessage(buildSlackMessage(entries, opts)))
+	opts.CategoryColors = map[string]string{"": "#36a64f"}
+	check("attachments", splitSlackMessage(buildSlackMessage(entries, opts)))
+}
+
+func TestSplitSlackMessageAttachments(t *testing.T) {
+	entries := make([]FilteredEntry, 60)
+	for i := range entries {
+		entries[i] = FilteredEntry{Title: "Article", Link: "https://domainincite.com/", Category: "dns"}
+	}
+	opts := slackOptions{Messages: englishMessages, CategoryColors: map[string]string{"dns": "#36a64f"}}
+
+	chunks := splitSlackMessage(buildSlackMessage(entries, opts))
+	if len(chunks) != 2 {
+		t.Fatalf("got %d messages, want 2", len(chunks))
+	}
+	total := 0
+	for i, chunk := range chunks {
+		n := len(chunk.Blocks)
+		for _, attachment := range chunk.Attachments {
+			if attachment.Color != "#36a64f" {
+				t.Errorf("message %d attachment color = %q, want it kept across the split", i, attachment.Color)
+			}
+			n += len(attachment.Blocks)
+		}
+		if n > maxSlackBlocks {
+			t.Errorf("message %d has %d blocks, want at most %d", i, n, maxSlackBlocks)
+		}
+		total += n
+	}
+	if want := 2 + 1 + len(entries); total != want {
+		t.Errorf("got %d blocks in total, want %d", total, want)
+	}
+}
+
+func TestParseColors(t *testing.T) {
+	got := parseColors("DNS=#36A64F, security=e01e5a, spam=red, short=#abc")
+	want := map[string]string{"dns": "#36a64f", "security": "#e01e5a", "short": "#abc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseColors = %v, want %v", got, want)
+	}
+}
+
+func TestRunHeartbeatReachesEveryNotifier(t *testing.T) {
+	t.Setenv("STATE_FILE", t.TempDir()+"/seen.json")
+	t.Setenv("NOTIFY_ON_EMPTY", "true")
+	feed := newFeedServer(t, "feed.xml")
+	var bodies []string
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.URL.Path+" "+string(data))
+	}))
+	defer sink.Close()
+
+	// Only Google Chat and the generic webhook are configured, so Slack is
+	// left out rather than failing the heartbeat.
+	cfg := Config{
+		FeedURLs:             []string{feed.URL},
+		Categories:           []string{"no-such-category"},
+		GoogleChatWebhookURL: sink.URL + "/chat",
+		GenericWebhookURL:    sink.URL + "/hook",
+	}
+	report := newRunReport(time.Now())
+	if err := run(t.Context(), feed.Client(), resolveConfig(t, cfg), report); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(bodies) != 2 || !strings.HasPrefix(bodies[0], "/chat ") || !strings.Contains(bodies[0], "No new") || bodies[1] != "/hook []" {
+		t.Errorf("requests = %q, want a heartbeat to Google Chat and an empty array to the webhook", bodies)
+	}
+	if len(report.Deliveries) != 2 {
+		t.Errorf("deliveries = %+v, want one per notifier", report.Deliveries)
+	}
+}
@@ -0,0 +1,114 @@
+package rssnotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// slackAPIBaseURL is the base URL of the Slack Web API. Tests point it at an
+// httptest server.
+var slackAPIBaseURL = "https://slack.com/api"
+
+// slackScheduleRequest is the payload for chat.scheduleMessage.
+// See: https://api.slack.com/methods/chat.scheduleMessage
+type slackScheduleRequest struct {
+	Channel     string            `json:"channel"`
+	PostAt      int64             `json:"post_at"` // Unix timestamp of when to post
+	Text        string            `json:"text"`
+	Blocks      []SlackBlock      `json:"blocks,omitempty"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+}
+
+// slackAPIResponse is the common envelope of Slack Web API responses.
+type slackAPIResponse struct {
+	OK                 bool   `json:"ok"`
+	Error              string `json:"error,omitempty"`
+	ScheduledMessageID string `json:"scheduled_message_id,omitempty"`
+	TS                 string `json:"ts,omitempty"`        // Timestamp identifying a posted message
+	CanvasID           string `json:"canvas_id,omitempty"` // Canvas created by conversations.canvases.create
+}
+
+// parseSendAt resolves a DIGEST_SEND_AT value into the time to post. It
+// accepts either an RFC3339 timestamp or a daily "HH:MM" time, which resolves
+// to the next occurrence of that time after now in now's location.
+func parseSendAt(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DIGEST_SEND_AT %q: expected HH:MM or RFC3339", value)
+	}
+	sendAt := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !sendAt.After(now) {
+		sendAt = sendAt.AddDate(0, 0, 1)
+	}
+	return sendAt, nil
+}
+
+// scheduleSlackMessage queues msg to be posted to channel at postAt using the
+// bot-token chat.scheduleMessage API.
+func scheduleSlackMessage(ctx context.Context, token, channel string, postAt time.Time, msg SlackMessage) error {
+	payload := slackScheduleRequest{
+		Channel:     channel,
+		PostAt:      postAt.Unix(),
+		Text:        msg.Text,
+		Blocks:      msg.Blocks,
+		Attachments: msg.Attachments,
+	}
+
+	infof("Scheduling Slack digest for %s...\n", postAt.Format(time.RFC3339))
+
+	apiResp, err := callSlackAPI(ctx, token, "chat.scheduleMessage", payload)
+	if err != nil {
+		return fmt.Errorf("error scheduling Slack message: %w", err)
+	}
+
+	infof("Successfully scheduled Slack digest (id %s).\n", apiResp.ScheduledMessageID)
+	return nil
+}
+
+// callSlackAPI posts payload as JSON to the named Slack Web API method,
+// authenticating with the bot token, and returns the decoded response. A
+// response with "ok": false is reported as an error.
+func callSlackAPI(ctx context.Context, token, method string, payload any) (slackAPIResponse, error) {
+	var apiResp slackAPIResponse
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return apiResp, fmt.Errorf("error marshalling %s payload to JSON: %w", method, err)
+	}
+
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackAPIBaseURL+"/"+method, bytes.NewReader(payloadBytes))
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s request: %w", method, err)
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("Authorization", "Bearer "+token)
+		return webhookClientFor(ctx).Do(req)
+	})
+	if err != nil {
+		return apiResp, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return apiResp, fmt.Errorf("error from Slack API with status %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return apiResp, fmt.Errorf("error parsing Slack API response: %w", err)
+	}
+	if !apiResp.OK {
+		return apiResp, fmt.Errorf("error from Slack API: %s", apiResp.Error)
+	}
+	return apiResp, nil
+}
@@ -0,0 +1,80 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseSendAt(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	now := time.Date(2024, time.June, 1, 10, 0, 0, 0, tokyo)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"18:30", time.Date(2024, time.June, 1, 18, 30, 0, 0, tokyo)},
+		{" 09:00 ", time.Date(2024, time.June, 2, 9, 0, 0, 0, tokyo)}, // Already passed today
+		{"10:00", time.Date(2024, time.June, 2, 10, 0, 0, 0, tokyo)},
+		{"2024-06-05T08:00:00Z", time.Date(2024, time.June, 5, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseSendAt(tt.value, now)
+		if err != nil {
+			t.Errorf("parseSendAt(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseSendAt(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+	for _, value := range []string{"6pm", "25:00", "2024-06-05"} {
+		if _, err := parseSendAt(value, now); err == nil {
+			t.Errorf("parseSendAt(%q): expected an error", value)
+		}
+	}
+}
+
+func TestResolveValidatesSendAt(t *testing.T) {
+	cfg := Config{Env: map[string]string{"DIGEST_SEND_AT": "6pm"}}
+	if _, err := cfg.resolve(); err == nil {
+		t.Error("expected an error for an invalid DIGEST_SEND_AT")
+	}
+	cfg.Env["DIGEST_SEND_AT"] = "18:00"
+	cfg.Env["DISPLAY_TIMEZONE"] = "Asia/Tokyo"
+	if _, err := cfg.resolve(); err != nil {
+		t.Errorf("resolve: %v", err)
+	}
+}
+
+func TestSlackScheduleUsesDisplayTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	var req slackScheduleRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		io.WriteString(w, `{"ok":true,"scheduled_message_id":"Q1"}`)
+	}))
+	defer srv.Close()
+	client, baseURL := webhookClient, slackAPIBaseURL
+	webhookClient, slackAPIBaseURL = srv.Client(), srv.URL
+	t.Cleanup(func() { webhookClient, slackAPIBaseURL = client, baseURL })
+
+	n := SlackNotifier{Options: slackOptions{BotToken: "xoxb-test", Channel: "C1", SendAt: "07:15", Location: tokyo}}
+	if err := n.Send(t.Context(), []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Unix(req.PostAt, 0).In(tokyo); got.Hour() != 7 || got.Minute() != 15 {
+		t.Errorf("scheduled for %v, want 07:15 in Asia/Tokyo", got)
+	}
+}
@@ -0,0 +1,108 @@
+package rssnotify
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// runSettings holds the HTTP and bookkeeping behaviour Main configures for a
+// run from the environment. It travels in the context rather than in package
+// variables, so FetchAndFilter and Notify, which are configured by Options
+// alone, always get the defaults.
+type runSettings struct {
+	MaxRetries        int           // Attempts per HTTP request; 0 uses defaultMaxRetries
+	UserAgent         string        // User-Agent sent on feed requests; "" uses defaultUserAgent
+	AcceptHeader      string        // Accept header sent on feed requests; "" uses defaultAcceptHeader
+	FeedAuthorization string        // Authorization header sent on feed requests, if any
+	WebhookTimeout    time.Duration // Bounds each webhook and Slack API request; 0 keeps webhookClient's
+	CaptureDir        string        // Save fetched feeds and sent payloads here, if set
+	Counter           *runCounter   // Accumulates the run's SUMMARY counters, if set
+	Attempts          *int          // Counts the attempts doWithRetry makes, if set; not safe for concurrent requests
+	SoftDeadline      *softDeadline // Stops new work once passed, if set
+}
+
+// settingsKey is the context key for runSettings.
+type settingsKey struct{}
+
+// withSettings returns a copy of ctx carrying s.
+func withSettings(ctx context.Context, s runSettings) context.Context {
+	return context.WithValue(ctx, settingsKey{}, s)
+}
+
+// settingsFrom returns the runSettings carried by ctx, or the zero value.
+func settingsFrom(ctx context.Context) runSettings {
+	s, _ := ctx.Value(settingsKey{}).(runSettings)
+	return s
+}
+
+// webhookClientFor returns webhookClient, bounded by the WebhookTimeout set
+// in ctx, if any.
+func webhookClientFor(ctx context.Context) *http.Client {
+	timeout := settingsFrom(ctx).WebhookTimeout
+	if timeout <= 0 {
+		return webhookClient
+	}
+	client := *webhookClient
+	client.Timeout = timeout
+	return &client
+}
+
+// notifierOptions holds the HTTP settings a notifier may override for its own
+// requests, as chat services differ in latency and error rates. Zero fields
+// keep the run's HTTP_TIMEOUT_SECONDS and HTTP_MAX_RETRIES.
+type notifierOptions struct {
+	Timeout    time.Duration // <PREFIX>_HTTP_TIMEOUT, in seconds
+	MaxRetries int           // <PREFIX>_MAX_RETRIES
+}
+
+// notifierOptions reads the overrides of the notifier whose settings start
+// with prefix, e.g. DISCORD for DISCORD_HTTP_TIMEOUT and DISCORD_MAX_RETRIES.
+func (c Config) notifierOptions(prefix string) notifierOptions {
+	return notifierOptions{
+		Timeout:    c.envSeconds(prefix + "_HTTP_TIMEOUT"),
+		MaxRetries: max(c.envInt(prefix+"_MAX_RETRIES", 0), 0),
+	}
+}
+
+// apply returns a copy of ctx whose runSettings carry o's overrides.
+func (o notifierOptions) apply(ctx context.Context) context.Context {
+	if o == (notifierOptions{}) {
+		return ctx
+	}
+	s := settingsFrom(ctx)
+	if o.Timeout > 0 {
+		s.WebhookTimeout = o.Timeout
+	}
+	if o.MaxRetries > 0 {
+		s.MaxRetries = o.MaxRetries
+	}
+	return withSettings(ctx, s)
+}
+
+// softDeadline is the SOFT_DEADLINE_SECONDS point of a run, after which no
+// new work (feed fetches, link checks, retries) is started. Unlike
+// RUN_TIMEOUT_SECONDS nothing in flight is cancelled, and whatever is ready
+// is still delivered.
+type softDeadline struct {
+	at      time.Time
+	tripped sync.Once
+}
+
+// newSoftDeadline returns a soft deadline passing at at.
+func newSoftDeadline(at time.Time) *softDeadline {
+	return &softDeadline{at: at}
+}
+
+// passed reports whether the deadline has passed, logging the first time it
+// has. A nil deadline never passes.
+func (d *softDeadline) passed() bool {
+	if d == nil || time.Now().Before(d.at) {
+		return false
+	}
+	d.tripped.Do(func() {
+		warnf("Soft deadline passed: finishing in-flight work without starting more, then delivering what's ready.\n")
+	})
+	return true
+}
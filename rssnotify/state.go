@@ -0,0 +1,446 @@
+package rssnotify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultStateFile is where seen entries are persisted when STATE_FILE is unset.
+const defaultStateFile = "./seen.json"
+
+// seenState is the on-disk format of the state file.
+type seenState struct {
+	Feeds   map[string][]string `json:"feeds"`             // Feed URL → keys (GUIDs, links or hashes) of its already-notified entries
+	Seen    []string            `json:"seen,omitempty"`    // Keys from before state was kept per feed; only read, for migration
+	LastRun time.Time           `json:"last_run,omitzero"` // Start of the last run that notified successfully
+	Newest  map[string]string   `json:"newest,omitempty"`  // Feed URL → key of a dateless feed's first entry, see runState
+
+	Published map[string]map[string]time.Time `json:"published,omitempty"` // Feed URL → key → publication date, see runState
+	Posted    []FilteredEntry                 `json:"posted,omitempty"`    // The entries of the last digest, see runState
+
+	Checkpoints map[string]checkpoint          `json:"checkpoints,omitempty"` // Feed URL → progress of an interrupted run, see runState
+	Sinks       map[string]map[string][]string `json:"sinks,omitempty"`       // Sink → feed URL → keys, like feeds, see runState
+
+	Matched  matchHistory      `json:"matched,omitzero"`   // See runState
+	Canvases map[string]string `json:"canvases,omitempty"` // Slack channel → canvas ID, see runState
+	Daily    dailyDigest       `json:"daily,omitzero"`     // See runState
+}
+
+// runState is what the state file carries from one run to the next.
+type runState struct {
+	Seen    seenEntries       // Already-notified entry keys of each feed
+	LastRun time.Time         // Start of the last run that notified successfully
+	Newest  map[string]string // Feed URL → key of the first entry of a dateless feed when last notified, under DATELESS_FEED_ORDER
+
+	Published map[string]map[string]time.Time // Feed URL → key → publication date of a notified entry, under DEDUP_WINDOW_DAYS
+	Posted    []FilteredEntry                 // The entries the last digest listed, under DIGEST_MODE=diff
+
+	Checkpoints map[string]checkpoint  // Feed URL → its last entry delivered by an interrupted run, under CHECKPOINT_BATCH
+	Sinks       map[string]seenEntries // Sink (see sinkID) → the entries sent to it, under DEDUP_SCOPE=sink
+
+	Matched  matchHistory      // How many entries recent runs sent, under GUARD_AGAINST_FLOOD
+	Canvases map[string]string // Slack channel → ID of the canvas showing its digest, under SLACK_CANVAS
+	Daily    dailyDigest       // Entries waiting for the next digest, under DIGEST_MODE=daily-fresh
+}
+
+// recordPublished records that the entry of the feed at feedURL keyed key
+// was published at published, unless it's undated.
+func (s runState) recordPublished(feedURL, key string, published time.Time) {
+	if published.IsZero() {
+		return
+	}
+	if s.Published[feedURL] == nil {
+		s.Published[feedURL] = make(map[string]time.Time)
+	}
+	s.Published[feedURL][key] = published.UTC()
+}
+
+// adoptSinks prepares the per-sink state of sinks under DEDUP_SCOPE=sink.
+// The first time, every sink takes over the global seen entries, so
+// switching scope sends nothing again; after that a sink without state of
+// its own, i.e. one added since, starts empty and is sent the backlog.
+func (s runState) adoptSinks(sinks []string) {
+	migrate := len(s.Sinks) == 0
+	for _, sink := range sinks {
+		if s.Sinks[sink] != nil {
+			continue
+		}
+		s.Sinks[sink] = make(seenEntries)
+		if migrate {
+			infof("Migrating the seen entries to per-sink state for %s.\n", sink)
+			for feedURL, keys := range s.Seen {
+				for key := range keys {
+					s.Sinks[sink].add(feedURL, key)
+				}
+			}
+		} else {
+			infof("No state for %s yet, so it is sent every matching entry.\n", sink)
+		}
+	}
+}
+
+// pruneBefore forgets the seen keys of entries published before cutoff and
+// returns how many it forgot. Keys without a recorded date, like those of
+// undated entries or recorded before DEDUP_WINDOW_DAYS was set, are kept.
+func (s runState) pruneBefore(cutoff time.Time) int {
+	pruned := 0
+	for feedURL, dates := range s.Published {
+		for key, published := range dates {
+			if published.Before(cutoff) {
+				delete(dates, key)
+				delete(s.Seen[feedURL], key)
+				for _, seen := range s.Sinks {
+					delete(seen[feedURL], key)
+				}
+				pruned++
+			}
+		}
+		if len(dates) == 0 {
+			delete(s.Published, feedURL)
+		}
+		if len(s.Seen[feedURL]) == 0 {
+			delete(s.Seen, feedURL)
+		}
+	}
+	return pruned
+}
+
+// seenEntries records the already-notified entry keys of each feed, so one
+// feed's entries never mask another's.
+type seenEntries map[string]map[string]bool
+
+// has reports whether key was notified for the feed at feedURL.
+func (s seenEntries) has(feedURL, key string) bool {
+	return s[feedURL][key]
+}
+
+// add records key as notified for the feed at feedURL.
+func (s seenEntries) add(feedURL, key string) {
+	if s[feedURL] == nil {
+		s[feedURL] = make(map[string]bool)
+	}
+	s[feedURL][key] = true
+}
+
+// lists returns the keys of each feed as a sorted list, for the state file.
+func (s seenEntries) lists() map[string][]string {
+	lists := make(map[string][]string, len(s))
+	for feedURL, keys := range s {
+		list := make([]string, 0, len(keys))
+		for key := range keys {
+			list = append(list, key)
+		}
+		sort.Strings(list) // Stable output keeps diffs of the file readable
+		lists[feedURL] = list
+	}
+	return lists
+}
+
+// seenFromLists is the inverse of lists.
+func seenFromLists(lists map[string][]string) seenEntries {
+	seen := make(seenEntries, len(lists))
+	for feedURL, keys := range lists {
+		for _, key := range keys {
+			seen.add(feedURL, key)
+		}
+	}
+	return seen
+}
+
+// loadState reads the state file at path. A missing file (e.g. on the first
+// run) yields empty state with a zero last run time. A state file written
+// before state was kept per feed is migrated by recording its keys for every
+// one of feedURLs, so nothing already notified is sent again.
+func loadState(path string, feedURLs []string) (runState, error) {
+	state := runState{
+		Seen:        make(seenEntries),
+		Newest:      make(map[string]string),
+		Published:   make(map[string]map[string]time.Time),
+		Checkpoints: make(map[string]checkpoint),
+		Sinks:       make(map[string]seenEntries),
+		Canvases:    make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return runState{}, fmt.Errorf("error reading state file: %w", err)
+	}
+
+	var stored seenState
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return runState{}, fmt.Errorf("error parsing state file: %w", err)
+	}
+	state.Seen = seenFromLists(stored.Feeds)
+	for sink, feeds := range stored.Sinks {
+		state.Sinks[sink] = seenFromLists(feeds)
+	}
+	if len(stored.Seen) > 0 {
+		infof("Migrating %d seen entries in %s to per-feed state.\n", len(stored.Seen), path)
+		for _, feedURL := range feedURLs {
+			for _, key := range stored.Seen {
+				state.Seen.add(feedURL, key)
+			}
+		}
+	}
+	maps.Copy(state.Newest, stored.Newest)
+	maps.Copy(state.Published, stored.Published)
+	state.Posted = stored.Posted
+	maps.Copy(state.Checkpoints, stored.Checkpoints)
+	state.Matched = stored.Matched
+	maps.Copy(state.Canvases, stored.Canvases)
+	state.Daily = stored.Daily
+	state.LastRun = stored.LastRun
+	return state, nil
+}
+
+// saveState writes state to the state file at path. The file is replaced
+// atomically so a crash mid-write can't corrupt it.
+func saveState(path string, state runState) error {
+	stored := seenState{
+		Feeds:     state.Seen.lists(),
+		LastRun:   state.LastRun.UTC(),
+		Newest:    state.Newest,
+		Published: state.Published,
+		Posted:    state.Posted,
+
+		Checkpoints: state.Checkpoints,
+		Matched:     state.Matched,
+		Canvases:    state.Canvases,
+		Daily:       state.Daily,
+	}
+	if len(state.Sinks) > 0 {
+		stored.Sinks = make(map[string]map[string][]string, len(state.Sinks))
+		for sink, seen := range state.Sinks {
+			stored.Sinks[sink] = seen.lists()
+		}
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing state file: %w", err)
+	}
+	return nil
+}
+
+// filterSinceLastRun returns the entries published after lastRun. Undated
+// entries are kept, leaving them to the seen keys.
+func filterSinceLastRun(entries []FilteredEntry, lastRun time.Time) []FilteredEntry {
+	var recent []FilteredEntry
+	for _, entry := range entries {
+		if entry.Published.IsZero() || entry.Published.After(lastRun) {
+			recent = append(recent, entry)
+		}
+	}
+	return recent
+}
+
+// filterSinceNewest drops the entries of each dateless feed (see
+// rankDateless) from the one newest recorded for it onwards, as the feed
+// lists them after that entry and so they are older. The recorded entry is
+// looked for among fetched, every entry of the run, since the seen keys have
+// usually removed it from entries. A feed whose recorded entry is no longer
+// listed is kept whole, leaving it to the seen keys.
+func filterSinceNewest(entries, fetched []FilteredEntry, newest map[string]string, key func(FilteredEntry) string) []FilteredEntry {
+	cutoff := make(map[string]int)
+	for _, entry := range fetched {
+		if entry.Rank > 0 && newest[entry.FeedURL] == key(entry) {
+			cutoff[entry.FeedURL] = entry.Rank
+		}
+	}
+	var recent []FilteredEntry
+	for _, entry := range entries {
+		if rank, ok := cutoff[entry.FeedURL]; !ok || entry.Rank < rank {
+			recent = append(recent, entry)
+		}
+	}
+	return recent
+}
+
+// recordNewest records the key of the first entry of each dateless feed
+// among fetched in newest, for filterSinceNewest on the next run.
+func recordNewest(newest map[string]string, fetched []FilteredEntry, key func(FilteredEntry) string) {
+	for _, entry := range fetched {
+		if entry.Rank == 1 {
+			newest[entry.FeedURL] = key(entry)
+		}
+	}
+}
+
+// DEDUP_SCOPE values selecting whose seen entries an entry is checked against.
+const (
+	dedupScopeGlobal = "global" // One set for all notifiers (the default)
+	dedupScopeSink   = "sink"   // A set per notifier, see runState.Sinks
+)
+
+// parseDedupScope validates a DEDUP_SCOPE value, falling back to
+// dedupScopeGlobal.
+func parseDedupScope(value string) string {
+	switch scope := strings.ToLower(strings.TrimSpace(value)); scope {
+	case "":
+		return dedupScopeGlobal
+	case dedupScopeGlobal, dedupScopeSink:
+		return scope
+	default:
+		warnf("Warning: unknown DEDUP_SCOPE %q, using %s\n", value, dedupScopeGlobal)
+		return dedupScopeGlobal
+	}
+}
+
+// SEEN_KEY values selecting what identifies an entry in the state file.
+const (
+	seenKeyGUID = "guid" // The GUID, falling back to the link (the default)
+	seenKeyLink = "link" // The link alone
+	seenKeyHash = "hash" // A hash of the title and description, for feeds recycling links
+)
+
+// parseSeenKey validates a SEEN_KEY value, falling back to seenKeyGUID.
+func parseSeenKey(value string) string {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "":
+		return seenKeyGUID
+	case seenKeyGUID, seenKeyLink, seenKeyHash:
+		return mode
+	default:
+		warnf("Warning: unknown SEEN_KEY %q, using %s\n", value, seenKeyGUID)
+		return seenKeyGUID
+	}
+}
+
+// checkSeenKey rejects a SEEN_KEY that contradicts DEDUP_FIELD. DEDUP_FIELD
+// identifies entries for both de-duplication and the state file; SEEN_KEY
+// only takes precedence for the state file when set to link or hash, which
+// is an error unless DEDUP_FIELD is left at guid (or, for link, also link).
+func checkSeenKey(mode, field string) error {
+	if mode == seenKeyGUID || field == dedupGUID || (mode == seenKeyLink && field == dedupLink) {
+		return nil
+	}
+	return fmt.Errorf("SEEN_KEY=%s conflicts with DEDUP_FIELD=%s: set only DEDUP_FIELD, or leave it at %s", mode, field, dedupGUID)
+}
+
+// seenKey returns the key recording entry in the state file under mode, with
+// field and level as for key. Hashes are prefixed so they can't collide
+// with GUIDs or links.
+func (entry FilteredEntry) seenKey(mode, field, level string) string {
+	switch mode {
+	case seenKeyLink:
+		return normalizeLink(entry.Link, level)
+	case seenKeyHash:
+		sum := sha256.Sum256([]byte(entry.Title + "\n" + entry.Description))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	default:
+		return entry.key(field, level)
+	}
+}
+
+// filterUnseen returns the entries whose keys under mode, with field and
+// level as for key, aren't in seen for their feed.
+func filterUnseen(entries []FilteredEntry, seen seenEntries, mode, field, level string) []FilteredEntry {
+	var unseen []FilteredEntry
+	for _, entry := range entries {
+		if !seen.hasEntry(entry.FeedURL, entry, mode, field, level) {
+			unseen = append(unseen, entry)
+		}
+	}
+	return unseen
+}
+
+// filterUnseenByAny returns the entries that at least one of sinks, each
+// sink's seen entries, wasn't sent, with mode, field and level as for key.
+func filterUnseenByAny(entries []FilteredEntry, sinks []seenEntries, mode, field, level string) []FilteredEntry {
+	var unseen []FilteredEntry
+	for _, entry := range entries {
+		if slices.ContainsFunc(sinks, func(seen seenEntries) bool {
+			return !seen.hasEntry(entry.FeedURL, entry, mode, field, level)
+		}) {
+			unseen = append(unseen, entry)
+		}
+	}
+	return unseen
+}
+
+// hasEntry reports whether entry was notified for the feed at feedURL. Unless
+// keyed by hash the link as given is checked too, as state files written
+// before GUIDs were tracked or links were normalized hold them.
+func (s seenEntries) hasEntry(feedURL string, entry FilteredEntry, mode, field, level string) bool {
+	return s.has(feedURL, entry.seenKey(mode, field, level)) || (mode != seenKeyHash && s.has(feedURL, strings.TrimSpace(entry.Link)))
+}
+
+// checkSeenOverlap warns about each feed none of whose entries are in the
+// seen state although the state isn't empty, the usual sign that the feed
+// URL or its GUIDs changed and every entry is about to be sent again. A feed
+// with no recorded entries of its own is compared against every feed's, so
+// a renamed feed URL is caught too. The feeds warned about are returned.
+func checkSeenOverlap(entries []FilteredEntry, seen seenEntries, mode, field, level string) []string {
+	all := make(map[string]bool)
+	for _, keys := range seen {
+		for key := range keys {
+			all[key] = true
+		}
+	}
+	if len(all) == 0 {
+		return nil // First run
+	}
+
+	var feeds []string
+	byFeed := make(map[string][]FilteredEntry)
+	for _, entry := range entries {
+		if _, ok := byFeed[entry.FeedURL]; !ok {
+			feeds = append(feeds, entry.FeedURL)
+		}
+		byFeed[entry.FeedURL] = append(byFeed[entry.FeedURL], entry)
+	}
+
+	var suspect []string
+	for _, feedURL := range feeds {
+		keys, own := seen[feedURL], len(seen[feedURL]) > 0
+		if !own {
+			keys = all
+		}
+		pool := seenEntries{feedURL: keys}
+		if slices.ContainsFunc(byFeed[feedURL], func(entry FilteredEntry) bool {
+			return pool.hasEntry(feedURL, entry, mode, field, level)
+		}) {
+			continue
+		}
+		suspect = append(suspect, feedURL)
+		if own {
+			warnf("Warning: none of the %d entries from %s are among the %d recorded for it in the state file; if its GUIDs or links changed they will all be sent again\n",
+				len(byFeed[feedURL]), feedURL, len(keys))
+		} else {
+			warnf("Warning: the state file records no entries for %s and none of its %d entries match the %d recorded for other feeds; if its URL changed they will all be sent again\n",
+				feedURL, len(byFeed[feedURL]), len(keys))
+		}
+	}
+	return suspect
+}
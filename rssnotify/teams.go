@@ -0,0 +1,190 @@
+package rssnotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// teamsMaxMessageBytes keeps each Teams message safely below the incoming
+// webhook's 28 KB payload limit.
+const teamsMaxMessageBytes = 24000
+
+// TeamsMessage wraps an Adaptive Card for a Teams incoming webhook
+// See: https://learn.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using
+type TeamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []TeamsAttachment `json:"attachments"`
+}
+
+type TeamsAttachment struct {
+	ContentType string            `json:"contentType"`
+	Content     TeamsAdaptiveCard `json:"content"`
+}
+
+type TeamsAdaptiveCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []TeamsTextBlock `json:"body"`
+}
+
+type TeamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+}
+
+// TeamsNotifier delivers the digest to a Microsoft Teams incoming webhook as
+// an Adaptive Card.
+type TeamsNotifier struct {
+	WebhookURL string
+	Options    slackOptions
+	HTTP       notifierOptions // Overrides of the run's timeout and retries
+	Report     *runReport
+}
+
+// Send posts entries to the Teams webhook, or the heartbeat message when
+// there are none and NOTIFY_ON_EMPTY is enabled.
+func (n TeamsNotifier) Send(ctx context.Context, entries []FilteredEntry) error {
+	n.Options = n.Options.withDefaults()
+	ctx = n.HTTP.apply(ctx)
+	deliveryStart := time.Now()
+	err := sendNotificationToTeams(ctx, n.WebhookURL, entries, n.Options)
+	n.Report.recordDelivery("teams", len(entries), time.Since(deliveryStart), err)
+	if err != nil {
+		return fmt.Errorf("error sending Teams notification: %w", err)
+	}
+	return nil
+}
+
+// newTeamsMessage returns a message holding a single card with blocks.
+func newTeamsMessage(blocks ...TeamsTextBlock) TeamsMessage {
+	return TeamsMessage{
+		Type: "message",
+		Attachments: []TeamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: TeamsAdaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    blocks,
+			},
+		}},
+	}
+}
+
+// teamsHeader renders the digest header as a large bold text block.
+func teamsHeader(opts slackOptions) TeamsTextBlock {
+	return TeamsTextBlock{Type: "TextBlock", Text: opts.headerText(), Wrap: true, Size: "Large", Weight: "Bolder"}
+}
+
+// teamsLinkText and teamsLinkURL escape a title and link for a markdown
+// [title](link), so a "]" or ")" in either can't end it early.
+var (
+	teamsLinkText = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`)
+	teamsLinkURL  = strings.NewReplacer("(", "%28", ")", "%29", " ", "%20")
+)
+
+// buildTeamsMessages formats entries as Adaptive Cards with a markdown link
+// per entry, splitting them across as many messages as needed to respect the
+// webhook's size limit. Only the first message carries the header.
+func buildTeamsMessages(entries []FilteredEntry, opts slackOptions) []TeamsMessage {
+	var messages []TeamsMessage
+	msg := newTeamsMessage(teamsHeader(opts))
+	size := estimateJSONSize(msg)
+
+	for _, entry := range entries {
+		text := fmt.Sprintf("- %s[%s](%s)", entry.changeMarker(), teamsLinkText.Replace(entry.Title), teamsLinkURL.Replace(entry.Link))
+		if !entry.Published.IsZero() {
+			text += " — " + opts.formatPublished(entry.Published)
+		}
+		block := TeamsTextBlock{Type: "TextBlock", Text: text, Wrap: true}
+		blockSize := estimateJSONSize(block) + 1 // Separating comma
+
+		card := &msg.Attachments[0].Content
+		if len(card.Body) > 0 && size+blockSize > teamsMaxMessageBytes {
+			messages = append(messages, msg)
+			msg = newTeamsMessage()
+			size = estimateJSONSize(msg)
+			card = &msg.Attachments[0].Content
+		}
+		card.Body = append(card.Body, block)
+		size += blockSize
+	}
+	return append(messages, msg)
+}
+
+// sendNotificationToTeams sends the list of filtered entries to a Teams
+// incoming webhook.
+func sendNotificationToTeams(ctx context.Context, webhookURL string, entries []FilteredEntry, opts slackOptions) error {
+	post := postTeamsMessage
+	if opts.DryRun {
+		post = printPayload
+	} else if webhookURL == "" {
+		errorf("Error: TEAMS_WEBHOOK_URL is not set. Cannot send Teams notification.\n")
+		return fmt.Errorf("TEAMS_WEBHOOK_URL is not configured")
+	}
+
+	if len(entries) == 0 {
+		if !opts.NotifyOnEmpty {
+			infof("No new DNS-related entries found to send to Teams.\n")
+			return nil
+		}
+		infof("Sending empty digest heartbeat to Teams...\n")
+		msg := newTeamsMessage(teamsHeader(opts), TeamsTextBlock{Type: "TextBlock", Text: opts.Messages.Empty, Wrap: true})
+		if _, err := post(ctx, webhookURL, msg); err != nil {
+			return err
+		}
+		infof("Successfully sent heartbeat to Teams.\n")
+		return nil
+	}
+
+	messages := buildTeamsMessages(entries, opts)
+	logFields(fmt.Sprintf("Sending %d DNS entries to Teams in %d message(s)...", len(entries), len(messages)),
+		"notifier", "teams", "entry_count", len(entries), "message_count", len(messages))
+
+	var errs []error
+	for i, msg := range messages {
+		if _, err := post(ctx, webhookURL, msg); err != nil {
+			errs = append(errs, fmt.Errorf("message %d of %d: %w", i+1, len(messages), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	infof("Successfully sent notification to Teams.\n")
+	return nil
+}
+
+// postTeamsMessage POSTs a single JSON payload to the Teams webhook. Any 2xx
+// response counts as success: Workflows webhooks answer 202 Accepted.
+func postTeamsMessage(ctx context.Context, webhookURL string, payload any) (string, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling Teams payload to JSON: %w", err)
+	}
+	capture(ctx, "teams-payload", "json", payloadBytes)
+
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		return postWebhook(ctx, webhookURL, "application/json", payloadBytes)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error sending message to Teams: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("error from Teams webhook with status %d: %s", resp.StatusCode, string(responseBody))
+	}
+	return string(responseBody), nil
+}
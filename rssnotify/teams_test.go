@@ -0,0 +1,68 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTeamsNotifierSend(t *testing.T) {
+	var got TeamsMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+	notifier := TeamsNotifier{WebhookURL: srv.URL, Options: slackOptions{Messages: englishMessages}, Report: newRunReport(time.Now())}
+	if err := notifier.Send(t.Context(), entries); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(got.Attachments) != 1 || got.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("got attachments %+v, want one Adaptive Card", got.Attachments)
+	}
+	body := got.Attachments[0].Content.Body
+	if len(body) != 2 || body[0].Text != "📰 Daily DNS News Digest (Domain Incite)" {
+		t.Fatalf("got card body %+v, want the header then one entry", body)
+	}
+	if want := "- [Registry raises prices](https://domainincite.com/1)"; body[1].Text != want {
+		t.Errorf("entry block = %q, want %q", body[1].Text, want)
+	}
+}
+
+func TestBuildTeamsMessagesEscapesLinks(t *testing.T) {
+	entries := []FilteredEntry{{Title: `[Update] .web (finally) \ done`, Link: "https://en.wikipedia.org/wiki/.web_(domain)"}}
+	msgs := buildTeamsMessages(entries, slackOptions{Messages: englishMessages})
+	want := `- [\[Update\] .web \(finally\) \\ done](https://en.wikipedia.org/wiki/.web_%28domain%29)`
+	if got := msgs[0].Attachments[0].Content.Body[1].Text; got != want {
+		t.Errorf("entry block = %q, want %q", got, want)
+	}
+}
+
+func TestBuildTeamsMessagesSplitsLargeDigests(t *testing.T) {
+	entries := make([]FilteredEntry, 400)
+	for i := range entries {
+		entries[i] = FilteredEntry{Title: "A reasonably long article title about DNS", Link: "https://domainincite.com/some/long/article/path"}
+	}
+
+	messages := buildTeamsMessages(entries, slackOptions{Messages: englishMessages})
+	if len(messages) < 2 {
+		t.Fatalf("got %d messages, want the digest split", len(messages))
+	}
+	total := 0
+	for i, msg := range messages {
+		if size := estimateJSONSize(msg); size > teamsMaxMessageBytes {
+			t.Errorf("message %d is %d bytes, want at most %d", i, size, teamsMaxMessageBytes)
+		}
+		total += len(msg.Attachments[0].Content.Body)
+	}
+	if total != len(entries)+1 {
+		t.Errorf("got %d blocks across messages, want %d entries plus the header", total, len(entries))
+	}
+}
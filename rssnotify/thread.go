@@ -0,0 +1,101 @@
+package rssnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// slackPostRequest is the payload for chat.postMessage.
+// See: https://api.slack.com/methods/chat.postMessage
+type slackPostRequest struct {
+	Channel     string            `json:"channel"`
+	Text        string            `json:"text"`
+	Blocks      []SlackBlock      `json:"blocks,omitempty"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+	ThreadTS    string            `json:"thread_ts,omitempty"` // Parent message to reply to
+}
+
+// buildThreadParent constructs the message starting a threaded digest: the
+// header and summary, with the entries left to the replies.
+func buildThreadParent(entries []FilteredEntry, opts slackOptions) SlackMessage {
+	var blocks []SlackBlock
+	mention := priorityMention(entries, opts)
+	if mention != "" {
+		blocks = append(blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: mention},
+		})
+	}
+	blocks = append(blocks,
+		SlackBlock{
+			Type: "header",
+			Text: &SlackText{Type: "plain_text", Text: opts.headerText(), Emoji: true},
+		},
+		SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: opts.Messages.render(opts.Messages.Summary, len(entries), "")},
+		},
+	)
+
+	first := fmt.Sprintf("<%s|%s>", entries[0].Link, escapeMrkdwn(entries[0].Title))
+	fallbackText := opts.Messages.render(opts.Messages.Fallback, len(entries), first)
+	if mention != "" {
+		fallbackText = mention + " " + fallbackText
+	}
+	return SlackMessage{Blocks: blocks, Text: fallbackText}
+}
+
+// buildThreadReply constructs the threaded reply for a single entry.
+func buildThreadReply(entry FilteredEntry, opts slackOptions) SlackMessage {
+	return SlackMessage{
+		Blocks: entryBlocks(entry, opts),
+		Text:   fmt.Sprintf("<%s|%s>", entry.Link, escapeMrkdwn(entry.Title)),
+	}
+}
+
+// sendThreadedToSlack posts the digest header to channel via chat.postMessage
+// and then each entry as a reply in its thread. Incoming webhooks don't
+// return the parent's timestamp, so threading needs the bot-token API.
+// Posts are spaced opts.PostDelay apart.
+func sendThreadedToSlack(ctx context.Context, token, channel string, entries []FilteredEntry, opts slackOptions) error {
+	parent := buildThreadParent(entries, opts)
+	logFields(fmt.Sprintf("Sending %d DNS entries to Slack as a thread...", len(entries)),
+		"notifier", "slack", "entry_count", len(entries))
+
+	apiResp, err := callSlackAPI(ctx, token, "chat.postMessage", slackPostRequest{
+		Channel: channel,
+		Text:    parent.Text,
+		Blocks:  parent.Blocks,
+	})
+	if err != nil {
+		return fmt.Errorf("error posting thread parent: %w", err)
+	}
+	if apiResp.TS == "" {
+		return fmt.Errorf("error posting thread parent: Slack API response has no ts")
+	}
+
+	var errs []error
+	sent := 0
+	for i, entry := range entries {
+		if err := pause(ctx, opts.PostDelay); err != nil {
+			errs = append(errs, fmt.Errorf("reply %d of %d: %w", i+1, len(entries), err))
+			break
+		}
+		reply := buildThreadReply(entry, opts)
+		_, err := callSlackAPI(ctx, token, "chat.postMessage", slackPostRequest{
+			Channel:  channel,
+			Text:     reply.Text,
+			Blocks:   reply.Blocks,
+			ThreadTS: apiResp.TS,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reply %d of %d: %w", i+1, len(entries), err))
+			continue
+		}
+		sent++
+	}
+
+	infof("Sent %d of %d threaded replies to Slack.\n", sent, len(entries))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,69 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendThreadedToSlack(t *testing.T) {
+	var requests []slackPostRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" {
+			t.Errorf("path = %s, want /chat.postMessage", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer xoxb-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var req slackPostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		requests = append(requests, req)
+		io.WriteString(w, `{"ok":true,"ts":"1715767200.000100"}`)
+	}))
+	defer srv.Close()
+	client, baseURL := webhookClient, slackAPIBaseURL
+	webhookClient, slackAPIBaseURL = srv.Client(), srv.URL
+	t.Cleanup(func() { webhookClient, slackAPIBaseURL = client, baseURL })
+
+	entries := []FilteredEntry{
+		{Title: "Registry raises prices", Link: "https://domainincite.com/1"},
+		{Title: "Root zone grows", Link: "https://domainincite.com/2"},
+	}
+	if err := sendThreadedToSlack(t.Context(), "xoxb-test", "C123", entries, slackOptions{Messages: englishMessages}); err != nil {
+		t.Fatalf("sendThreadedToSlack: %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want a parent and 2 replies", len(requests))
+	}
+	if parent := requests[0]; parent.ThreadTS != "" || parent.Channel != "C123" || parent.Blocks[0].Type != "header" {
+		t.Errorf("parent = %+v, want an unthreaded header message in C123", parent)
+	}
+	for i, reply := range requests[1:] {
+		if reply.ThreadTS != "1715767200.000100" {
+			t.Errorf("reply %d thread_ts = %q, want the parent's ts", i, reply.ThreadTS)
+		}
+		if want := formatEntryLine(entries[i], slackOptions{}); reply.Blocks[0].Text.Text != want {
+			t.Errorf("reply %d = %q, want %q", i, reply.Blocks[0].Text.Text, want)
+		}
+	}
+}
+
+func TestSendThreadedToSlackAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"ok":false,"error":"channel_not_found"}`)
+	}))
+	defer srv.Close()
+	client, baseURL := webhookClient, slackAPIBaseURL
+	webhookClient, slackAPIBaseURL = srv.Client(), srv.URL
+	t.Cleanup(func() { webhookClient, slackAPIBaseURL = client, baseURL })
+
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1"}}
+	if err := sendThreadedToSlack(t.Context(), "xoxb-test", "C123", entries, slackOptions{Messages: englishMessages}); err == nil {
+		t.Fatal("expected an error when Slack rejects the parent message")
+	}
+}
@@ -0,0 +1,164 @@
+package rssnotify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeChildren walks body token by token and hands every child of the
+// container element (e.g. rss>channel) to handle. When handle fails, or the
+// XML inside a child is malformed, decoding restarts at the next sibling
+// named resume and the broken element is counted as skipped, so one bad item
+// doesn't lose the rest of the feed.
+func decodeChildren(body []byte, container []string, resume string, handle func(d *xml.Decoder, start xml.StartElement) error) (skipped int, err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	base := int64(0)              // Offset in body of the decoder's input
+	var opened []xml.StartElement // Container elements entered so far
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return skipped, nil
+		}
+		if err == nil {
+			start, ok := tok.(xml.StartElement)
+			switch {
+			case !ok:
+				if _, closed := tok.(xml.EndElement); closed && len(opened) > 0 {
+					opened = opened[:len(opened)-1]
+				}
+				continue
+			case len(opened) < len(container):
+				if start.Name.Local != container[len(opened)] {
+					return skipped, fmt.Errorf("error parsing XML: unexpected <%s>", start.Name.Local)
+				}
+				opened = append(opened, start.Copy())
+				continue
+			}
+			if err = handle(decoder, start); err == nil {
+				continue
+			}
+			if start.Name.Local == resume {
+				skipped++
+			}
+		}
+
+		// Only failures inside the container can be skipped over.
+		if len(opened) < len(container) {
+			return skipped, fmt.Errorf("error parsing XML: %w", err)
+		}
+		next := nextElement(body, base+decoder.InputOffset(), resume)
+		if next < 0 {
+			warnf("Warning: malformed XML at end of feed, ignoring the remainder: %v\n", err)
+			return skipped, nil
+		}
+		warnf("Warning: skipping malformed <%s>: %v\n", resume, err)
+
+		// Re-open the container, with its namespace declarations, so the new
+		// decoder resolves prefixed elements like the original one.
+		prefix := containerPrefix(opened)
+		decoder = xml.NewDecoder(io.MultiReader(strings.NewReader(prefix), bytes.NewReader(body[next:])))
+		base = next - int64(len(prefix))
+		opened = nil
+	}
+}
+
+// nextElement returns the offset of the next <name> start tag in body at or
+// after from, or -1 if there is none.
+func nextElement(body []byte, from int64, name string) int64 {
+	tag := []byte("<" + name)
+	for from < int64(len(body)) {
+		i := bytes.Index(body[from:], tag)
+		if i < 0 {
+			return -1
+		}
+		at := from + int64(i)
+		end := at + int64(len(tag))
+		if end < int64(len(body)) && strings.ContainsRune(" \t\r\n>/", rune(body[end])) {
+			return at
+		}
+		from = end
+	}
+	return -1
+}
+
+// containerPrefix renders start tags reopening opened, keeping only the
+// namespace declarations.
+func containerPrefix(opened []xml.StartElement) string {
+	var b strings.Builder
+	for _, start := range opened {
+		b.WriteString("<" + start.Name.Local)
+		for _, attr := range start.Attr {
+			var name string
+			switch {
+			case attr.Name.Space == "xmlns":
+				name = "xmlns:" + attr.Name.Local
+			case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+				name = "xmlns"
+			default:
+				continue
+			}
+			b.WriteString(" " + name + `="`)
+			xml.EscapeText(&b, []byte(attr.Value))
+			b.WriteString(`"`)
+		}
+		b.WriteString(">")
+	}
+	return b.String()
+}
+
+// parseRSSTolerant decodes an RSS document item by item, skipping malformed
+// items rather than failing the whole feed.
+func parseRSSTolerant(body []byte) (Channel, int, error) {
+	var channel Channel
+	skipped, err := decodeChildren(body, []string{"rss", "channel"}, "item", func(d *xml.Decoder, start xml.StartElement) error {
+		switch {
+		case start.Name.Local == "item":
+			var item Item
+			if err := d.DecodeElement(&item, &start); err != nil {
+				return err
+			}
+			channel.Items = append(channel.Items, item)
+			return nil
+		case start.Name.Local == "title" && start.Name.Space == "":
+			return d.DecodeElement(&channel.Title, &start)
+		case start.Name.Local == "link" && start.Name.Space == "":
+			return d.DecodeElement(&channel.Link, &start)
+		default:
+			return d.Skip()
+		}
+	})
+	return channel, skipped, err
+}
+
+// parseAtomTolerant decodes an Atom document entry by entry, skipping
+// malformed entries rather than failing the whole feed.
+func parseAtomTolerant(body []byte) (Atom, int, error) {
+	var feed Atom
+	skipped, err := decodeChildren(body, []string{"feed"}, "entry", func(d *xml.Decoder, start xml.StartElement) error {
+		switch start.Name.Local {
+		case "entry":
+			var entry AtomEntry
+			if err := d.DecodeElement(&entry, &start); err != nil {
+				return err
+			}
+			feed.Entries = append(feed.Entries, entry)
+			return nil
+		case "title":
+			return d.DecodeElement(&feed.Title, &start)
+		case "link":
+			var link AtomLink
+			if err := d.DecodeElement(&link, &start); err != nil {
+				return err
+			}
+			feed.Links = append(feed.Links, link)
+			return nil
+		default:
+			return d.Skip()
+		}
+	})
+	return feed, skipped, err
+}
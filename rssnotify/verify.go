@@ -0,0 +1,118 @@
+package rssnotify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// linkCheckConcurrency bounds how many HEAD requests are in flight at once.
+const linkCheckConcurrency = 5
+
+// linkStatus is the outcome of verifying a single link.
+type linkStatus int
+
+const (
+	linkOK          linkStatus = iota // Link responded with a non-error status
+	linkDead                          // Link responded with 4xx/5xx or its own request timed out
+	linkUnreachable                   // Link could not be checked (network error, or the run's context ended)
+	linkUnchecked                     // Link wasn't checked as the soft deadline had passed
+)
+
+// verifyLinks issues a HEAD request for each entry's link and drops entries
+// whose link is dead. Entries that can't be verified due to a network error
+// are kept unless keepUnreachable is false. Each distinct link is only checked
+// once per run. Once ctx is done, e.g. when RUN_TIMEOUT expires, the
+// remaining and in-flight checks count as unreachable rather than dead. Past
+// the soft deadline no further check is started and the remaining entries are
+// kept unchecked.
+func verifyLinks(ctx context.Context, entries []FilteredEntry, keepUnreachable bool) []FilteredEntry {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	var links []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !seen[entry.Link] {
+			seen[entry.Link] = true
+			links = append(links, entry.Link)
+		}
+	}
+
+	results := make(map[string]linkStatus, len(links))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, linkCheckConcurrency)
+
+	for _, link := range links {
+		wg.Add(1)
+		go func(link string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			status := linkUnchecked
+			if !settingsFrom(ctx).SoftDeadline.passed() {
+				status = checkLink(ctx, client, link)
+			}
+			mu.Lock()
+			results[link] = status
+			mu.Unlock()
+		}(link)
+	}
+	wg.Wait()
+
+	var verified []FilteredEntry
+	for _, entry := range entries {
+		switch results[entry.Link] {
+		case linkDead:
+			infof("Dropping entry with dead link: '%s' - %s\n", entry.Title, entry.Link)
+			continue
+		case linkUnreachable:
+			if !keepUnreachable {
+				infof("Dropping entry with unverifiable link: '%s' - %s\n", entry.Title, entry.Link)
+				continue
+			}
+			infof("Keeping entry with unverifiable link: '%s' - %s\n", entry.Title, entry.Link)
+		}
+		verified = append(verified, entry)
+	}
+	return verified
+}
+
+// checkLink performs a HEAD request against link, falling back to GET for
+// servers that don't allow HEAD. Only the per-request client timeout marks a
+// link dead: ctx ending says nothing about the link itself.
+func checkLink(ctx context.Context, client *http.Client, link string) linkStatus {
+	resp, err := requestLink(ctx, client, http.MethodHead, link)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		resp, err = requestLink(ctx, client, http.MethodGet, link)
+	}
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return linkUnreachable
+		}
+		var netErr interface{ Timeout() bool }
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return linkDead
+		}
+		return linkUnreachable
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return linkDead
+	}
+	return linkOK
+}
+
+// requestLink sends a bodiless request for link, bound to ctx.
+func requestLink(ctx context.Context, client *http.Client, method, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
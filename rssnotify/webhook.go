@@ -0,0 +1,73 @@
+package rssnotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier POSTs the filtered entries as a plain JSON array to an
+// arbitrary URL, for downstream services that do their own formatting.
+type WebhookNotifier struct {
+	URL     string
+	Options slackOptions
+	HTTP    notifierOptions // Overrides of the run's timeout and retries
+	Report  *runReport
+}
+
+// Send posts entries to the webhook. Any 2xx response counts as success. An
+// empty digest sends nothing unless NotifyOnEmpty asks for a heartbeat, which
+// is posted as an empty array.
+func (n WebhookNotifier) Send(ctx context.Context, entries []FilteredEntry) error {
+	ctx = n.HTTP.apply(ctx)
+	if len(entries) == 0 {
+		if !n.Options.NotifyOnEmpty {
+			infof("No new DNS-related entries found to send to the generic webhook.\n")
+			return nil
+		}
+		entries = []FilteredEntry{} // Marshalled as [] rather than null
+	}
+
+	deliveryStart := time.Now()
+	err := postEntriesToWebhook(ctx, n.URL, entries, n.Options.DryRun)
+	n.Report.recordDelivery("webhook", len(entries), time.Since(deliveryStart), err)
+	if err != nil {
+		return fmt.Errorf("error sending generic webhook notification: %w", err)
+	}
+	return nil
+}
+
+// postEntriesToWebhook marshals entries and POSTs them to webhookURL, or
+// prints them when dryRun is set.
+func postEntriesToWebhook(ctx context.Context, webhookURL string, entries []FilteredEntry, dryRun bool) error {
+	if dryRun {
+		_, err := printPayload(ctx, webhookURL, entries)
+		return err
+	}
+
+	payloadBytes, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("error marshalling entries to JSON: %w", err)
+	}
+	capture(ctx, "webhook-payload", "json", payloadBytes)
+
+	logFields(fmt.Sprintf("Sending %d DNS entries to the generic webhook...", len(entries)),
+		"notifier", "webhook", "entry_count", len(entries))
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		return postWebhook(ctx, webhookURL, "application/json", payloadBytes)
+	})
+	if err != nil {
+		return fmt.Errorf("error sending entries to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error from webhook with status %d: %s", resp.StatusCode, string(responseBody))
+	}
+	infof("Successfully sent entries to the generic webhook.\n")
+	return nil
+}
@@ -0,0 +1,46 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifierSend(t *testing.T) {
+	var got []FilteredEntry
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	published := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	entries := []FilteredEntry{{Title: "Registry raises prices", Link: "https://domainincite.com/1", Published: published}}
+	notifier := WebhookNotifier{URL: srv.URL, Report: newRunReport(time.Now())}
+	if err := notifier.Send(t.Context(), entries); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(got) != 1 || got[0].Link != entries[0].Link || !got[0].Published.Equal(published) {
+		t.Errorf("webhook received %+v, want %+v", got, entries)
+	}
+}
+
+func TestWebhookNotifierSendErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	notifier := WebhookNotifier{URL: srv.URL, Report: newRunReport(time.Now())}
+	if err := notifier.Send(t.Context(), []FilteredEntry{{Title: "A", Link: "https://example.com/a"}}); err == nil {
+		t.Fatal("expected an error for a 400 response")
+	}
+}
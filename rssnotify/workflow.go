@@ -0,0 +1,121 @@
+package rssnotify
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Slack payload formats selectable via SLACK_FORMAT.
+const (
+	slackFormatBlocks   = "blocks"   // Block Kit message for incoming webhooks
+	slackFormatWorkflow = "workflow" // Flat variables for Workflow Builder webhook triggers
+)
+
+// workflowSources are the values that can be sent to a Slack workflow, in
+// their default order.
+var workflowSources = []string{"count", "first_title", "first_link", "digest_text"}
+
+// workflowVariable maps a digest value to the workflow input variable name it
+// is sent as.
+type workflowVariable struct {
+	Source string // One of workflowSources
+	Name   string // Workflow input variable name
+}
+
+// parseSlackFormat validates a SLACK_FORMAT value, falling back to Block Kit.
+func parseSlackFormat(value string) string {
+	switch format := strings.ToLower(strings.TrimSpace(value)); format {
+	case "":
+		return slackFormatBlocks
+	case slackFormatBlocks, slackFormatWorkflow:
+		return format
+	default:
+		warnf("Warning: unknown SLACK_FORMAT %q, using %s\n", value, slackFormatBlocks)
+		return slackFormatBlocks
+	}
+}
+
+// parseWorkflowVariables parses a SLACK_WORKFLOW_VARIABLES value: a
+// comma-separated list of sources, each optionally renamed with "=", e.g.
+// "count=article_count,digest_text". An empty value sends every source under
+// its own name.
+func parseWorkflowVariables(value string) []workflowVariable {
+	var variables []workflowVariable
+	for _, part := range splitList(value) {
+		source, name, found := strings.Cut(part, "=")
+		source = strings.TrimSpace(source)
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			name = source
+		}
+		if !isWorkflowSource(source) {
+			warnf("Warning: unknown workflow variable source %q, skipping\n", source)
+			continue
+		}
+		variables = append(variables, workflowVariable{Source: source, Name: name})
+	}
+
+	if len(variables) == 0 {
+		for _, source := range workflowSources {
+			variables = append(variables, workflowVariable{Source: source, Name: source})
+		}
+	}
+	return variables
+}
+
+// isWorkflowSource reports whether source is a known workflow value.
+func isWorkflowSource(source string) bool {
+	for _, s := range workflowSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// buildWorkflowPayload builds the flat key/value payload for a Slack workflow
+// webhook trigger. Workflow variables are text, so every value is a string.
+func buildWorkflowPayload(entries []FilteredEntry, variables []workflowVariable) map[string]string {
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("• %s%s - %s", entry.changeMarker(), entry.Title, entry.Link))
+	}
+
+	values := map[string]string{
+		"count":       strconv.Itoa(len(entries)),
+		"first_title": entries[0].Title,
+		"first_link":  entries[0].Link,
+		"digest_text": strings.Join(lines, "\n"),
+	}
+
+	payload := make(map[string]string, len(variables))
+	for _, v := range variables {
+		payload[v.Name] = values[v.Source]
+	}
+	return payload
+}
+
+// sendWorkflowToSlack posts the digest to a Slack Workflow Builder webhook
+// trigger as flat variables rather than Block Kit.
+func sendWorkflowToSlack(ctx context.Context, webhookURL string, entries []FilteredEntry, variables []workflowVariable) error {
+	if webhookURL == "" {
+		errorf("Error: SLACK_WEBHOOK_URL is not set. Cannot send Slack notification.\n")
+		return fmt.Errorf("SLACK_WEBHOOK_URL is not configured")
+	}
+
+	if len(entries) == 0 {
+		infof("No new DNS-related entries found to send to Slack.\n")
+		return nil
+	}
+
+	infof("Sending %d DNS entries to Slack workflow...\n", len(entries))
+
+	responseBody, err := postSlackMessage(ctx, webhookURL, buildWorkflowPayload(entries, variables))
+	if err != nil {
+		return err
+	}
+	infof("Successfully triggered Slack workflow. Response: %s\n", responseBody)
+	return nil
+}
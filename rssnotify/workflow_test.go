@@ -0,0 +1,57 @@
+package rssnotify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWorkflowVariables(t *testing.T) {
+	got := parseWorkflowVariables("count=article_count, digest_text ,bogus,first_link=")
+	want := []workflowVariable{
+		{Source: "count", Name: "article_count"},
+		{Source: "digest_text", Name: "digest_text"},
+		{Source: "first_link", Name: "first_link"}, // An empty rename keeps the name
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWorkflowVariables = %+v, want %+v", got, want)
+	}
+	if got := parseWorkflowVariables(""); len(got) != len(workflowSources) {
+		t.Errorf("parseWorkflowVariables(\"\") = %+v, want every source", got)
+	}
+}
+
+func TestSlackNotifierWorkflowTrigger(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	entries := []FilteredEntry{
+		{Title: "Registry raises prices", Link: "https://domainincite.com/1"},
+		{Title: "Root zone grows", Link: "https://domainincite.com/2"},
+	}
+	opts := slackOptions{Format: parseSlackFormat(" Workflow "), WorkflowVariables: parseWorkflowVariables("count=article_count,first_title,digest_text")}
+	report := newRunReport(time.Now())
+	if err := (SlackNotifier{WebhookURL: srv.URL, Options: opts, Report: report}).Send(t.Context(), entries); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	want := map[string]string{
+		"article_count": "2",
+		"first_title":   "Registry raises prices",
+		"digest_text":   "• Registry raises prices - https://domainincite.com/1\n• Root zone grows - https://domainincite.com/2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %v, want %v", got, want)
+	}
+	if len(report.Deliveries) != 1 || report.Deliveries[0].Notifier != "slack-workflow" {
+		t.Errorf("deliveries = %+v, want a slack-workflow delivery", report.Deliveries)
+	}
+}